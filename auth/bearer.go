@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-camp/httpc"
+	"github.com/go-camp/httpc/request"
+)
+
+const headerAuthorization = "Authorization"
+
+// BearerAuthBuilder sets the bearer token of Provider to the Authorization header.
+//
+// BearerAuthBuilder should be added before RetryBuilder.
+// If the response is a ResponseError with status 401 and Provider implements TokenInvalidator,
+// the token is invalidated and the request is sent once more with a new token,
+// the request body must implement io.Seeker for the retry.
+type BearerAuthBuilder struct {
+	Provider TokenProvider
+}
+
+type bearerAuthError struct {
+	While string
+	Err   error
+}
+
+func (e *bearerAuthError) Error() string {
+	return fmt.Sprintf("request bearer auth builder, %s failed, %v", e.While, e.Err)
+}
+
+func (e *bearerAuthError) Unwrap() error {
+	return e.Err
+}
+
+func (b BearerAuthBuilder) ID() string { return "BearerAuthBuilder" }
+
+func (b BearerAuthBuilder) Builder(build httpc.BuildFunc) httpc.BuildFunc {
+	return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
+		return b.build(ctx, req, build)
+	}
+}
+
+func (b BearerAuthBuilder) build(ctx context.Context, req *httpc.Request, build httpc.BuildFunc) (
+	output interface{}, md httpc.Metadata, err error,
+) {
+	token, err := b.Provider.Token(ctx)
+	if err != nil {
+		return nil, md, &bearerAuthError{While: "get token", Err: err}
+	}
+	invalidator, canInvalidate := b.Provider.(TokenInvalidator)
+
+	// a body without rewinding can't be sent again, so the 401 response is returned as is.
+	rewind := func() error { return nil }
+	if canInvalidate && req.Body != nil && req.Body != http.NoBody {
+		rewind, _ = request.RewindFunc(req.Body)
+	}
+
+	req.Header.Set(headerAuthorization, "Bearer "+token.AccessToken)
+	output, md, err = build(ctx, req)
+	if !canInvalidate || !isUnauthorized(err) || rewind == nil {
+		return
+	}
+
+	invalidator.Invalidate(token)
+	if rerr := rewind(); rerr != nil {
+		return output, md, &bearerAuthError{While: "rewind body", Err: rerr}
+	}
+	request.CloseResult(output, md, err)
+	token, err = b.Provider.Token(ctx)
+	if err != nil {
+		return nil, md, &bearerAuthError{While: "get token", Err: err}
+	}
+	req.Header.Set(headerAuthorization, "Bearer "+token.AccessToken)
+	return build(ctx, req)
+}
+
+func isUnauthorized(err error) bool {
+	var respErr *httpc.ResponseError
+	return errors.As(err, &respErr) && respErr.Response != nil &&
+		respErr.Response.StatusCode == http.StatusUnauthorized
+}
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-camp/httpc"
+)
+
+type bearerTestBody struct {
+	io.Reader
+	closed *int
+}
+
+func (b bearerTestBody) Close() error {
+	*b.closed++
+	return nil
+}
+
+func newBearerTestHandler(provider TokenProvider, body io.Reader, validToken string, sent *[]string, closed *int) httpc.Handler {
+	return httpc.Handler{
+		Serializer: func(serialize httpc.SerializeFunc) httpc.SerializeFunc {
+			return func(ctx context.Context, input httpc.SerializeInput) (output interface{}, md httpc.Metadata, err error) {
+				input.Request = &httpc.Request{
+					Request: &http.Request{
+						Method: http.MethodPost,
+						URL:    &url.URL{Scheme: "http", Host: "example.com", Path: "/items"},
+						Header: http.Header{},
+					},
+					Body: body,
+				}
+				return serialize(ctx, input)
+			}
+		},
+		Builder: BearerAuthBuilder{Provider: provider}.Builder,
+		Deserializer: func(deserialize httpc.DeserializeFunc) httpc.DeserializeFunc {
+			return func(req *http.Request) (output httpc.DeserializeOutput, md httpc.Metadata, err error) {
+				output, md, err = deserialize(req)
+				if err == nil && output.Response.StatusCode != http.StatusOK {
+					err = &httpc.ResponseError{Response: output.Response, Err: errors.New("unauthorized")}
+				}
+				return
+			}
+		},
+		Do: func(req *http.Request) (*http.Response, error) {
+			var b []byte
+			if req.Body != nil {
+				b, _ = io.ReadAll(req.Body)
+			}
+			*sent = append(*sent, req.Header.Get("Authorization")+" "+string(b))
+			statusCode := http.StatusOK
+			if req.Header.Get("Authorization") != "Bearer "+validToken {
+				statusCode = http.StatusUnauthorized
+			}
+			return &http.Response{
+				StatusCode: statusCode,
+				Header:     http.Header{},
+				Body:       bearerTestBody{Reader: strings.NewReader(""), closed: closed},
+			}, nil
+		},
+	}
+}
+
+func TestBearerAuthBuilder(t *testing.T) {
+	testCases := []struct {
+		Name       string
+		Provider   func(s *tokenServer) TokenProvider
+		Body       io.Reader
+		ValidToken string
+
+		ExpectSent    []string
+		ExpectErr     bool
+		ExpectFetches int
+		ExpectClosed  int
+	}{
+		{
+			Name:          "cached token",
+			Provider:      func(s *tokenServer) TokenProvider { return &CachingTokenProvider{Provider: s.Provider()} },
+			Body:          strings.NewReader("body"),
+			ValidToken:    "token-1",
+			ExpectSent:    []string{"Bearer token-1 body"},
+			ExpectFetches: 1,
+		},
+		{
+			Name:          "retry once with new token",
+			Provider:      func(s *tokenServer) TokenProvider { return &CachingTokenProvider{Provider: s.Provider()} },
+			Body:          strings.NewReader("body"),
+			ValidToken:    "token-2",
+			ExpectSent:    []string{"Bearer token-1 body", "Bearer token-2 body"},
+			ExpectFetches: 2,
+			ExpectClosed:  1,
+		},
+		{
+			Name:          "retry only once",
+			Provider:      func(s *tokenServer) TokenProvider { return &CachingTokenProvider{Provider: s.Provider()} },
+			Body:          strings.NewReader("body"),
+			ValidToken:    "token-3",
+			ExpectSent:    []string{"Bearer token-1 body", "Bearer token-2 body"},
+			ExpectErr:     true,
+			ExpectFetches: 2,
+			ExpectClosed:  1,
+		},
+		{
+			Name:          "retry with no body",
+			Provider:      func(s *tokenServer) TokenProvider { return &CachingTokenProvider{Provider: s.Provider()} },
+			Body:          http.NoBody,
+			ValidToken:    "token-2",
+			ExpectSent:    []string{"Bearer token-1 ", "Bearer token-2 "},
+			ExpectFetches: 2,
+			ExpectClosed:  1,
+		},
+		{
+			Name:          "no retry without rewindable body",
+			Provider:      func(s *tokenServer) TokenProvider { return &CachingTokenProvider{Provider: s.Provider()} },
+			Body:          io.LimitReader(strings.NewReader("body"), 4),
+			ValidToken:    "token-2",
+			ExpectSent:    []string{"Bearer token-1 body"},
+			ExpectErr:     true,
+			ExpectFetches: 1,
+		},
+		{
+			Name:       "no retry without invalidator",
+			Provider:   func(s *tokenServer) TokenProvider { return StaticTokenProvider{AccessToken: "static"} },
+			Body:       strings.NewReader("body"),
+			ValidToken: "token-1",
+			ExpectSent: []string{"Bearer static body"},
+			ExpectErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			s := newTokenServer(t, 3600)
+			var sent []string
+			var closed int
+			h := newBearerTestHandler(tc.Provider(s), tc.Body, tc.ValidToken, &sent, &closed)
+			_, _, err := h.Handle(context.Background(), nil)
+			if (err != nil) != tc.ExpectErr {
+				t.Fatalf("expect err is %v, got %v", tc.ExpectErr, err)
+			}
+			if strings.Join(sent, "|") != strings.Join(tc.ExpectSent, "|") {
+				t.Fatalf("expect sent is %q, got %q", tc.ExpectSent, sent)
+			}
+			if s.Fetches() != tc.ExpectFetches {
+				t.Fatalf("expect fetches is %d, got %d", tc.ExpectFetches, s.Fetches())
+			}
+			if closed != tc.ExpectClosed {
+				t.Fatalf("expect closed responses is %d, got %d", tc.ExpectClosed, closed)
+			}
+		})
+	}
+}
+
+func TestBearerAuthBuilderProviderError(t *testing.T) {
+	s := newTokenServer(t, 3600)
+	p := s.Provider()
+	p.ClientSecret = "wrong"
+	var sent []string
+	var closed int
+	h := newBearerTestHandler(p, nil, "token-1", &sent, &closed)
+	_, _, err := h.Handle(context.Background(), nil)
+	var oauth2Err *OAuth2Error
+	if !errors.As(err, &oauth2Err) {
+		t.Fatalf("expect OAuth2Error, got %v", err)
+	}
+	if len(sent) != 0 {
+		t.Fatalf("expect no request is sent, got %q", sent)
+	}
+}
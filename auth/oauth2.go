@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OAuth2Error is the error response of the token endpoint.
+type OAuth2Error struct {
+	StatusCode  int
+	Code        string
+	Description string
+}
+
+func (e *OAuth2Error) Error() string {
+	return fmt.Sprintf("oauth2 token request failed, status %d, %s: %s", e.StatusCode, e.Code, e.Description)
+}
+
+type oauth2Error struct {
+	While string
+	Err   error
+}
+
+func (e *oauth2Error) Error() string {
+	return fmt.Sprintf("oauth2 client credentials provider, %s failed, %v", e.While, e.Err)
+}
+
+func (e *oauth2Error) Unwrap() error {
+	return e.Err
+}
+
+// ClientCredentialsProvider provides tokens by the OAuth2 client credentials grant.
+//
+// ClientCredentialsProvider requests a new token for every call,
+// it is usually wrapped by CachingTokenProvider.
+type ClientCredentialsProvider struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// EndpointParams are the extra parameters of the token request.
+	EndpointParams url.Values
+	// If Do is nil, then http.DefaultClient.Do is used.
+	Do func(req *http.Request) (*http.Response, error)
+	// If Now is nil, then time.Now is used.
+	Now func() time.Time
+}
+
+func (p ClientCredentialsProvider) do() func(req *http.Request) (*http.Response, error) {
+	if p.Do == nil {
+		return http.DefaultClient.Do
+	}
+	return p.Do
+}
+
+func (p ClientCredentialsProvider) now() time.Time {
+	if p.Now == nil {
+		return time.Now()
+	}
+	return p.Now()
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (p ClientCredentialsProvider) Token(ctx context.Context) (token Token, err error) {
+	form := url.Values{}
+	for k, vs := range p.EndpointParams {
+		form[k] = vs
+	}
+	form.Set("grant_type", "client_credentials")
+	if len(p.Scopes) > 0 {
+		form.Set("scope", strings.Join(p.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return token, &oauth2Error{While: "new request", Err: err}
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+
+	start := p.now()
+	resp, err := p.do()(req)
+	if err != nil {
+		return token, &oauth2Error{While: "send request", Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return token, &oauth2Error{While: "read response", Err: err}
+	}
+	var tr tokenResponse
+	jerr := json.Unmarshal(body, &tr)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || tr.Error != "" {
+		return token, &OAuth2Error{StatusCode: resp.StatusCode, Code: tr.Error, Description: tr.ErrorDescription}
+	}
+	if jerr != nil {
+		return token, &oauth2Error{While: "decode response", Err: jerr}
+	}
+	if tr.AccessToken == "" {
+		return token, &oauth2Error{While: "decode response", Err: fmt.Errorf("access_token is empty")}
+	}
+	if tr.TokenType != "" && !strings.EqualFold(tr.TokenType, "bearer") {
+		return token, &oauth2Error{While: "decode response", Err: fmt.Errorf("unsupported token type %q", tr.TokenType)}
+	}
+
+	token.AccessToken = tr.AccessToken
+	if tr.ExpiresIn > 0 {
+		token.Expiry = start.Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
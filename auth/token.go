@@ -0,0 +1,147 @@
+// Package auth provides the bearer token authentication of httpc.
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Token is an access token.
+type Token struct {
+	AccessToken string
+	// Expiry is the expiration time of the token, the zero value means the token never expires.
+	Expiry time.Time
+}
+
+func (t Token) expiresWithin(now time.Time, d time.Duration) bool {
+	return !t.Expiry.IsZero() && !now.Add(d).Before(t.Expiry)
+}
+
+// TokenProvider provides access tokens.
+type TokenProvider interface {
+	Token(ctx context.Context) (Token, error)
+}
+
+// TokenInvalidator is an optional interface of TokenProvider.
+// BearerAuthBuilder invalidates the token rejected by the server and retries once if it is implemented.
+type TokenInvalidator interface {
+	Invalidate(token Token)
+}
+
+// StaticTokenProvider always provides the same token.
+type StaticTokenProvider struct {
+	AccessToken string
+}
+
+func (p StaticTokenProvider) Token(ctx context.Context) (Token, error) {
+	return Token{AccessToken: p.AccessToken}, nil
+}
+
+// Default values of CachingTokenProvider.
+const (
+	DefaultTokenRefreshWindow = 1 * time.Minute
+	DefaultTokenExpiryLeeway  = 10 * time.Second
+)
+
+type tokenCall struct {
+	done  chan struct{}
+	token Token
+	err   error
+}
+
+// CachingTokenProvider caches the token of Provider until shortly before it expires.
+//
+// A token expiring within RefreshWindow is still returned, while it is refreshed in the background.
+// A token expiring within ExpiryLeeway is not returned, the callers wait for the refresh.
+// Concurrent refreshes are merged into one call of Provider,
+// which uses a context detached from the callers, so Provider should have its own timeout.
+type CachingTokenProvider struct {
+	Provider TokenProvider
+	// If RefreshWindow is 0, then DefaultTokenRefreshWindow is used.
+	RefreshWindow time.Duration
+	// If ExpiryLeeway is 0, then DefaultTokenExpiryLeeway is used.
+	ExpiryLeeway time.Duration
+	// If Now is nil, then time.Now is used.
+	Now func() time.Time
+
+	mux   sync.Mutex
+	token *Token
+	call  *tokenCall
+}
+
+var _ TokenInvalidator = (*CachingTokenProvider)(nil)
+
+func (p *CachingTokenProvider) refreshWindow() time.Duration {
+	if p.RefreshWindow == 0 {
+		return DefaultTokenRefreshWindow
+	}
+	return p.RefreshWindow
+}
+
+func (p *CachingTokenProvider) expiryLeeway() time.Duration {
+	if p.ExpiryLeeway == 0 {
+		return DefaultTokenExpiryLeeway
+	}
+	return p.ExpiryLeeway
+}
+
+func (p *CachingTokenProvider) now() time.Time {
+	if p.Now == nil {
+		return time.Now()
+	}
+	return p.Now()
+}
+
+func (p *CachingTokenProvider) Token(ctx context.Context) (Token, error) {
+	p.mux.Lock()
+	now := p.now()
+	if p.token != nil && !p.token.expiresWithin(now, p.expiryLeeway()) {
+		token := *p.token
+		if p.call == nil && token.expiresWithin(now, p.refreshWindow()) {
+			p.refresh()
+		}
+		p.mux.Unlock()
+		return token, nil
+	}
+	call := p.call
+	if call == nil {
+		call = p.refresh()
+	}
+	p.mux.Unlock()
+
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return Token{}, ctx.Err()
+	}
+}
+
+// refresh starts a refresh, p.mux must be held.
+func (p *CachingTokenProvider) refresh() *tokenCall {
+	call := &tokenCall{done: make(chan struct{})}
+	p.call = call
+	go func() {
+		defer close(call.done)
+		call.token, call.err = p.Provider.Token(context.Background())
+
+		p.mux.Lock()
+		defer p.mux.Unlock()
+		if call.err == nil {
+			token := call.token
+			p.token = &token
+		}
+		p.call = nil
+	}()
+	return call
+}
+
+// Invalidate drops the cached token if it is token.
+func (p *CachingTokenProvider) Invalidate(token Token) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.token != nil && p.token.AccessToken == token.AccessToken {
+		p.token = nil
+	}
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type tokenServer struct {
+	*httptest.Server
+	fetches int32
+	block   chan struct{}
+}
+
+func newTokenServer(t *testing.T, expiresIn int) *tokenServer {
+	s := &tokenServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.block != nil {
+			<-s.block
+		}
+		clientID, clientSecret, _ := r.BasicAuth()
+		if r.Method != http.MethodPost || clientID != "id" || clientSecret != "secret" ||
+			r.FormValue("grant_type") != "client_credentials" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_client","error_description":"bad client"}`)
+			return
+		}
+		n := atomic.AddInt32(&s.fetches, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":%d,"scope":%q}`,
+			n, expiresIn, r.FormValue("scope"))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *tokenServer) Fetches() int {
+	return int(atomic.LoadInt32(&s.fetches))
+}
+
+func (s *tokenServer) Provider() ClientCredentialsProvider {
+	return ClientCredentialsProvider{
+		TokenURL:     s.URL,
+		ClientID:     "id",
+		ClientSecret: "secret",
+		Scopes:       []string{"read", "write"},
+	}
+}
+
+func TestClientCredentialsProvider(t *testing.T) {
+	s := newTokenServer(t, 3600)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := s.Provider()
+	p.Now = func() time.Time { return now }
+
+	token, err := p.Token(context.Background())
+	if err != nil {
+		t.Fatalf("expect no err, got %v", err)
+	}
+	if token.AccessToken != "token-1" {
+		t.Fatalf("expect access token is token-1, got %s", token.AccessToken)
+	}
+	if expect := now.Add(time.Hour); !token.Expiry.Equal(expect) {
+		t.Fatalf("expect expiry is %v, got %v", expect, token.Expiry)
+	}
+
+	p.ClientSecret = "wrong"
+	_, err = p.Token(context.Background())
+	var oauth2Err *OAuth2Error
+	if !errors.As(err, &oauth2Err) {
+		t.Fatalf("expect OAuth2Error, got %v", err)
+	}
+	if oauth2Err.StatusCode != http.StatusUnauthorized || oauth2Err.Code != "invalid_client" ||
+		oauth2Err.Description != "bad client" {
+		t.Fatalf("expect invalid_client error, got %+v", oauth2Err)
+	}
+}
+
+func TestStaticTokenProvider(t *testing.T) {
+	token, err := StaticTokenProvider{AccessToken: "static"}.Token(context.Background())
+	if err != nil {
+		t.Fatalf("expect no err, got %v", err)
+	}
+	if token.AccessToken != "static" || !token.Expiry.IsZero() {
+		t.Fatalf("expect static token never expires, got %+v", token)
+	}
+}
+
+func TestCachingTokenProvider(t *testing.T) {
+	s := newTokenServer(t, 3600)
+	var mux sync.Mutex
+	now := time.Now()
+	clock := func() time.Time {
+		mux.Lock()
+		defer mux.Unlock()
+		return now
+	}
+	provider := s.Provider()
+	provider.Now = clock
+	p := &CachingTokenProvider{Provider: provider, Now: clock}
+	advance := func(d time.Duration) {
+		mux.Lock()
+		defer mux.Unlock()
+		now = now.Add(d)
+	}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		token, err := p.Token(ctx)
+		if err != nil {
+			t.Fatalf("expect no err, got %v", err)
+		}
+		if token.AccessToken != "token-1" {
+			t.Fatalf("expect access token is token-1, got %s", token.AccessToken)
+		}
+	}
+	if s.Fetches() != 1 {
+		t.Fatalf("expect fetches is 1, got %d", s.Fetches())
+	}
+
+	// within the refresh window, the cached token is returned and refreshed in the background.
+	advance(time.Hour - 30*time.Second)
+	token, err := p.Token(ctx)
+	if err != nil {
+		t.Fatalf("expect no err, got %v", err)
+	}
+	if token.AccessToken != "token-1" {
+		t.Fatalf("expect access token is token-1, got %s", token.AccessToken)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		token, _ = p.Token(ctx)
+		if token.AccessToken == "token-2" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expect background refresh, got %s", token.AccessToken)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if s.Fetches() != 2 {
+		t.Fatalf("expect fetches is 2, got %d", s.Fetches())
+	}
+
+	// within the expiry leeway, the callers wait for the refresh.
+	advance(time.Hour - 5*time.Second)
+	token, err = p.Token(ctx)
+	if err != nil {
+		t.Fatalf("expect no err, got %v", err)
+	}
+	if token.AccessToken != "token-3" {
+		t.Fatalf("expect access token is token-3, got %s", token.AccessToken)
+	}
+
+	p.Invalidate(Token{AccessToken: "token-1"})
+	if token, _ = p.Token(ctx); token.AccessToken != "token-3" {
+		t.Fatalf("expect stale invalidation is ignored, got %s", token.AccessToken)
+	}
+	p.Invalidate(token)
+	if token, _ = p.Token(ctx); token.AccessToken != "token-4" {
+		t.Fatalf("expect access token is token-4, got %s", token.AccessToken)
+	}
+}
+
+func TestCachingTokenProviderSingleflight(t *testing.T) {
+	s := newTokenServer(t, 3600)
+	s.block = make(chan struct{})
+	p := &CachingTokenProvider{Provider: s.Provider()}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	tokens := make([]Token, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens[i], errs[i] = p.Token(context.Background())
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(s.block)
+	wg.Wait()
+
+	for i := range tokens {
+		if errs[i] != nil {
+			t.Fatalf("expect no err, got %v", errs[i])
+		}
+		if tokens[i].AccessToken != "token-1" {
+			t.Fatalf("expect access token is token-1, got %s", tokens[i].AccessToken)
+		}
+	}
+	if s.Fetches() != 1 {
+		t.Fatalf("expect fetches is 1, got %d", s.Fetches())
+	}
+}
+
+func TestCachingTokenProviderContext(t *testing.T) {
+	s := newTokenServer(t, 3600)
+	s.block = make(chan struct{})
+	defer close(s.block)
+	p := &CachingTokenProvider{Provider: s.Provider()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := p.Token(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect deadline exceeded, got %v", err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"path"
 
 	"github.com/go-camp/httpc"
 	"github.com/go-camp/httpc/request"
@@ -185,3 +186,97 @@ func ExampleHandler_order() {
 	// output: &{}
 	// err: <nil>
 }
+
+func ExampleTypedHandler() {
+	type Input struct{ Name string }
+	type Output struct{ Greeting string }
+
+	h := httpc.TypedHandler[Input, Output]{
+		Handler: httpc.Handler{
+			Initializer: httpc.ComposeInitializer(
+				// validate input here.
+				httpc.TypedInitializer[Input](func(initialize httpc.TypedInitializeFunc[Input]) httpc.TypedInitializeFunc[Input] {
+					return func(ctx context.Context, input *Input) (output interface{}, md httpc.Metadata, err error) {
+						if input == nil || input.Name == "" {
+							return output, md, httpc.NewParamRequiredError("Name")
+						}
+						return initialize(ctx, input)
+					}
+				}).Initializer,
+			),
+
+			Serializer: httpc.ComposeSerializer(
+				httpc.TypedSerializer[Input](func(serialize httpc.TypedSerializeFunc[Input]) httpc.TypedSerializeFunc[Input] {
+					return func(ctx context.Context, input httpc.TypedSerializeInput[Input]) (output interface{}, md httpc.Metadata, err error) {
+						req, err := httpc.NewRequest(ctx, http.MethodGet, "http://example.com/hello/"+input.Input.Name, nil)
+						if err != nil {
+							return output, md, &httpc.SerializationError{Err: err}
+						}
+						input.Request = req
+						return serialize(ctx, input)
+					}
+				}).Serializer,
+			),
+
+			Builder: httpc.ComposeBuilder(
+				request.RetryBuilder{}.Builder,
+			),
+
+			Deserializer: httpc.ComposeDeserializer(
+				func(deserialize httpc.DeserializeFunc) httpc.DeserializeFunc {
+					return func(req *http.Request) (output httpc.DeserializeOutput, md httpc.Metadata, err error) {
+						output, md, err = deserialize(req)
+						if err != nil {
+							return
+						}
+						output.Output = &Output{Greeting: "hello " + path.Base(req.URL.Path)}
+						return
+					}
+				},
+			),
+
+			Do: func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: 200,
+					Header:     http.Header{},
+					Body:       http.NoBody,
+				}, nil
+			},
+		},
+	}
+
+	output, _, err := h.Handle(context.Background(), &Input{Name: "gopher"})
+	fmt.Println("output:", output.Greeting)
+	fmt.Println("err:", err)
+
+	_, _, err = h.Handle(context.Background(), &Input{})
+	fmt.Println("err:", err)
+	// output:
+	// output: hello gopher
+	// err: <nil>
+	// err: missing required param, Name.
+}
+
+func ExampleStack() {
+	stack := &httpc.Stack{}
+	stack.Build.Add(request.UserAgentBuilder{}.ID(), request.UserAgentBuilder{}.Builder, httpc.After)
+	stack.Build.Add(request.RequestIDBuilder{}.ID(), request.RequestIDBuilder{}.Builder, httpc.After)
+	stack.Build.Add(request.ContentMD5Builder{}.ID(), request.ContentMD5Builder{}.Builder, httpc.After)
+	stack.Build.Add(request.RetryBuilder{}.ID(), request.RetryBuilder{}.Builder, httpc.After)
+
+	// insert a signer after RequestIDBuilder.
+	signer := func(build httpc.BuildFunc) httpc.BuildFunc {
+		return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
+			req.Header.Set("Authorization", "signature")
+			return build(ctx, req)
+		}
+	}
+	stack.Build.Insert("Signer", signer, request.RequestIDBuilder{}.ID(), httpc.After)
+
+	// remove ContentMD5Builder.
+	stack.Build.Remove(request.ContentMD5Builder{}.ID())
+
+	fmt.Println(stack.Build.List())
+	// output:
+	// [UserAgentBuilder RequestIDBuilder Signer RetryBuilder]
+}
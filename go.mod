@@ -1,5 +1,5 @@
 module github.com/go-camp/httpc
 
-go 1.17
+go 1.18
 
 require github.com/go-camp/retry v0.0.0-20210813070521-91835365fb35
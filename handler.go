@@ -89,6 +89,22 @@ func ComposeBuilder(builder ...Builder) Builder {
 	}
 }
 
+type FinalizeFunc func(ctx context.Context, req *Request) (output interface{}, md Metadata, err error)
+
+type Finalizer func(FinalizeFunc) FinalizeFunc
+
+func ComposeFinalizer(finalizer ...Finalizer) Finalizer {
+	if len(finalizer) == 1 {
+		return finalizer[0]
+	}
+	return func(finalize FinalizeFunc) FinalizeFunc {
+		for i := len(finalizer) - 1; i >= 0; i-- {
+			finalize = finalizer[i](finalize)
+		}
+		return finalize
+	}
+}
+
 type DeserializeOutput struct {
 	Output   interface{}
 	Response *http.Response
@@ -113,23 +129,27 @@ func ComposeDeserializer(deserializer ...Deserializer) Deserializer {
 // Handler make a http request and process the response step by step.
 //
 // Call chain:
-//   Initializer -> Serializer -> Builder -> Deserializer -> Do
+//   Initializer -> Serializer -> Builder -> Finalizer -> Deserializer -> Do
 //
 // Return chain:
-//   Initializer <- Serializer <- Builder <- Deserializer <- Do
+//   Initializer <- Serializer <- Builder <- Finalizer <- Deserializer <- Do
 //
 // OperationError wraps any of:
 //   1. SerializationError
 //   2. InvalidParamsError
 //   3. RequestSendError
 //   4. ResponseError
-//   5. others
+//   5. PanicError
+//   6. others
 //
 // ResponseError wraps any of:
 //   1. DeserializationError
 //   2. APIError
 //   3. GenericAPIError
 //   4. others
+//
+// Each stage is composed of the stage field followed by the steps of the same stage in Stack.
+// A nil stage field is skipped.
 type Handler struct {
 	// Initializer initializes the input.
 	// Examples:
@@ -143,12 +163,20 @@ type Handler struct {
 	Serializer Serializer
 
 	// Builder add extra headers to the request.
+	// Builder runs once per call.
 	// Exmaples:
 	//   1. Set Content-Length header.
 	//   2. Set User-Agent header.
 	//   3. Retry the request.
 	Builder Builder
 
+	// Finalizer runs once per attempt after the retry in Builder.
+	// Exmaples:
+	//   1. Sign the request.
+	//   2. Set attempt-scoped request id header.
+	//   3. Set clock-skew headers.
+	Finalizer Finalizer
+
 	// Deserializer decode the response into the output/metadata/err.
 	// Examples:
 	//   1. Decode response body into the output.
@@ -156,6 +184,10 @@ type Handler struct {
 	//   3. Set raw response to the metadata.
 	Deserializer Deserializer
 
+	// Stack holds the named steps of every stage.
+	// The steps run after the stage fields above.
+	Stack *Stack
+
 	// Do is http.Client's Do method.
 	Do func(req *http.Request) (*http.Response, error)
 }
@@ -171,17 +203,25 @@ func (w deserializeWrapper) Deserialize(req *http.Request) (output DeserializeOu
 	return
 }
 
-type buildWrapper struct {
+type finalizeWrapper struct {
 	Deserialize DeserializeFunc
 }
 
-func (w buildWrapper) Build(ctx context.Context, req *Request) (output interface{}, md Metadata, err error) {
+func (w finalizeWrapper) Finalize(ctx context.Context, req *Request) (output interface{}, md Metadata, err error) {
 	var dout DeserializeOutput
 	dout, md, err = w.Deserialize(req.Build())
 	output = dout.Output
 	return
 }
 
+type buildWrapper struct {
+	Finalize FinalizeFunc
+}
+
+func (w buildWrapper) Build(ctx context.Context, req *Request) (output interface{}, md Metadata, err error) {
+	return w.Finalize(ctx, req)
+}
+
 type serializeWrapper struct {
 	Build BuildFunc
 }
@@ -198,11 +238,103 @@ func (w initializeWrapper) Initialize(ctx context.Context, input interface{}) (o
 	return w.Serialize(ctx, SerializeInput{Input: input})
 }
 
-func (h Handler) Handle(ctx context.Context, input interface{}) (output interface{}, md Metadata, err error) {
-	deserialize := h.Deserializer(deserializeWrapper{Do: h.Do}.Deserialize)
-	build := h.Builder(buildWrapper{Deserialize: deserialize}.Build)
-	serialize := h.Serializer(serializeWrapper{Build: build}.Serialize)
-	initialize := h.Initializer(initializeWrapper{Serialize: serialize}.Initialize)
+func (h Handler) initializer() Initializer {
+	var initializers []Initializer
+	if h.Initializer != nil {
+		initializers = append(initializers, h.Initializer)
+	}
+	if h.Stack != nil {
+		initializers = append(initializers, h.Stack.Initialize.steps...)
+	}
+	return ComposeInitializer(initializers...)
+}
+
+func (h Handler) serializer() Serializer {
+	var serializers []Serializer
+	if h.Serializer != nil {
+		serializers = append(serializers, h.Serializer)
+	}
+	if h.Stack != nil {
+		serializers = append(serializers, h.Stack.Serialize.steps...)
+	}
+	return ComposeSerializer(serializers...)
+}
+
+func (h Handler) builder() Builder {
+	var builders []Builder
+	if h.Builder != nil {
+		builders = append(builders, h.Builder)
+	}
+	if h.Stack != nil {
+		builders = append(builders, h.Stack.Build.steps...)
+	}
+	return ComposeBuilder(builders...)
+}
+
+func (h Handler) finalizer() Finalizer {
+	var finalizers []Finalizer
+	if h.Finalizer != nil {
+		finalizers = append(finalizers, h.Finalizer)
+	}
+	if h.Stack != nil {
+		finalizers = append(finalizers, h.Stack.Finalize.steps...)
+	}
+	return ComposeFinalizer(finalizers...)
+}
+
+func (h Handler) deserializer() Deserializer {
+	var deserializers []Deserializer
+	if h.Deserializer != nil {
+		deserializers = append(deserializers, h.Deserializer)
+	}
+	if h.Stack != nil {
+		deserializers = append(deserializers, h.Stack.Deserialize.steps...)
+	}
+	return ComposeDeserializer(deserializers...)
+}
+
+// Options are the per-call options of Handler.Handle.
+type Options struct {
+	// Stack is a copy of Handler.Stack, changing it only affects the current call.
+	Stack *Stack
+
+	// Do is a copy of Handler.Do.
+	Do func(req *http.Request) (*http.Response, error)
+}
+
+func (h Handler) withOptions(optFns ...func(*Options)) Handler {
+	if len(optFns) == 0 {
+		return h
+	}
+
+	var opts Options
+	if h.Stack == nil {
+		opts.Stack = &Stack{}
+	} else {
+		opts.Stack = h.Stack.Clone()
+	}
+	opts.Do = h.Do
+	for _, optFn := range optFns {
+		optFn(&opts)
+	}
+
+	h.Stack = opts.Stack
+	h.Do = opts.Do
+	return h
+}
+
+// Handle handles the input.
+// optFns change the options only for this call, h is never mutated,
+// so Handle can be called concurrently.
+func (h Handler) Handle(ctx context.Context, input interface{}, optFns ...func(*Options)) (
+	output interface{}, md Metadata, err error,
+) {
+	h = h.withOptions(optFns...)
+	deserialize := h.deserializer()(deserializeWrapper{Do: h.Do}.Deserialize)
+	finalize := h.finalizer()(finalizeWrapper{Deserialize: deserialize}.Finalize)
+	build := h.builder()(buildWrapper{Finalize: finalize}.Build)
+	serialize := h.serializer()(serializeWrapper{Build: build}.Serialize)
+	initialize := h.initializer()(initializeWrapper{Serialize: serialize}.Initialize)
 	return initialize(ctx, input)
 }
 
@@ -222,3 +354,20 @@ func (e *OperationError) Unwrap() error { return e.Err }
 func (e *OperationError) Error() string {
 	return fmt.Sprintf("%s operation error: %s, %v", e.Service, e.Operation, e.Err)
 }
+
+// PanicError wraps the value recovered from a panic in a stage.
+type PanicError struct {
+	Stage Stage
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%s stage panic: %v", e.Stage, e.Value)
+}
+
+// Unwrap returns Value if Value is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
@@ -0,0 +1,109 @@
+package httpc
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestHandlerOptions(t *testing.T) {
+	stack := &Stack{}
+	stack.Serialize.Add("Request", func(serialize SerializeFunc) SerializeFunc {
+		return func(ctx context.Context, input SerializeInput) (interface{}, Metadata, error) {
+			input.Request, _ = NewRequest(ctx, http.MethodGet, "http://example.com", nil)
+			return serialize(ctx, input)
+		}
+	}, After)
+
+	var doCalls, optionDoCalls int
+	h := Handler{
+		Stack: stack,
+		Do: func(req *http.Request) (*http.Response, error) {
+			doCalls++
+			return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+		},
+	}
+
+	var header string
+	_, _, err := h.Handle(context.Background(), nil, func(o *Options) {
+		o.Stack.Build.Add("Header", func(build BuildFunc) BuildFunc {
+			return func(ctx context.Context, req *Request) (interface{}, Metadata, error) {
+				req.Header.Set("X-Test", "1")
+				return build(ctx, req)
+			}
+		}, After)
+		o.Do = func(req *http.Request) (*http.Response, error) {
+			optionDoCalls++
+			header = req.Header.Get("X-Test")
+			return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+		}
+	})
+	if err != nil {
+		t.Fatalf("expect no err, got %s", err)
+	}
+	if doCalls != 0 || optionDoCalls != 1 {
+		t.Fatalf("expect option do is called, got do %d, option do %d", doCalls, optionDoCalls)
+	}
+	if header != "1" {
+		t.Fatalf("expect header is 1, got %s", header)
+	}
+	if ids := h.Stack.Build.List(); len(ids) != 0 {
+		t.Fatalf("expect handler stack is not mutated, got %v", ids)
+	}
+
+	_, _, err = h.Handle(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("expect no err, got %s", err)
+	}
+	if doCalls != 1 {
+		t.Fatalf("expect do is called, got %d", doCalls)
+	}
+}
+
+func TestHandlerFinalizer(t *testing.T) {
+	var calls []string
+	stack := &Stack{}
+	stack.Serialize.Add("Request", func(serialize SerializeFunc) SerializeFunc {
+		return func(ctx context.Context, input SerializeInput) (interface{}, Metadata, error) {
+			input.Request, _ = NewRequest(ctx, http.MethodGet, "http://example.com", nil)
+			return serialize(ctx, input)
+		}
+	}, After)
+	stack.Build.Add("Retry", func(build BuildFunc) BuildFunc {
+		return func(ctx context.Context, req *Request) (output interface{}, md Metadata, err error) {
+			calls = append(calls, "build")
+			for i := 0; i < 2; i++ {
+				output, md, err = build(ctx, req.Clone(req.Context()))
+			}
+			return
+		}
+	}, After)
+	stack.Finalize.Add("Sign", func(finalize FinalizeFunc) FinalizeFunc {
+		return func(ctx context.Context, req *Request) (interface{}, Metadata, error) {
+			calls = append(calls, "finalize")
+			return finalize(ctx, req)
+		}
+	}, After)
+	stack.Deserialize.Add("Deserialize", func(deserialize DeserializeFunc) DeserializeFunc {
+		return func(req *http.Request) (DeserializeOutput, Metadata, error) {
+			calls = append(calls, "deserialize")
+			return deserialize(req)
+		}
+	}, After)
+
+	h := Handler{
+		Stack: stack,
+		Do: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+		},
+	}
+	_, _, err := h.Handle(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("expect no err, got %s", err)
+	}
+	expectCalls := []string{"build", "finalize", "deserialize", "finalize", "deserialize"}
+	if !reflect.DeepEqual(expectCalls, calls) {
+		t.Fatalf("expect calls are %v, got %v", expectCalls, calls)
+	}
+}
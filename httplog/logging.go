@@ -0,0 +1,367 @@
+// Package httplog provides the structured logging middleware of httpc.
+package httplog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-camp/httpc"
+	"github.com/go-camp/httpc/request"
+	"github.com/go-camp/httpc/response"
+)
+
+// Record is the log record of an attempt.
+type Record struct {
+	Time      time.Time
+	Service   string
+	Operation string
+	// Attempt is the attempt number starting from 1.
+	Attempt int
+	Method  string
+	URL     string
+	// RequestID is the request id got by response.RequestIDDeserializer, or from the request header if absent.
+	RequestID      string
+	RequestHeader  http.Header
+	RequestBody    string
+	StatusCode     int
+	ResponseHeader http.Header
+	ResponseBody   string
+	Latency        time.Duration
+	// Err is the error of sending the request.
+	Err error
+}
+
+// String formats r as space separated key=value pairs.
+func (r Record) String() string {
+	var b strings.Builder
+	add := func(key string, value string) {
+		if value == "" {
+			return
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(key)
+		b.WriteByte('=')
+		if strings.ContainsAny(value, " \t\r\n\"=") {
+			value = strconv.Quote(value)
+		}
+		b.WriteString(value)
+	}
+	add("service", r.Service)
+	add("operation", r.Operation)
+	add("attempt", strconv.Itoa(r.Attempt))
+	add("method", r.Method)
+	add("url", r.URL)
+	if r.StatusCode > 0 {
+		add("status", strconv.Itoa(r.StatusCode))
+	}
+	add("latency", r.Latency.String())
+	add("request_id", r.RequestID)
+	if r.Err != nil {
+		add("error", r.Err.Error())
+	}
+	add("request_body", r.RequestBody)
+	add("response_body", r.ResponseBody)
+	return b.String()
+}
+
+// Logger logs the records.
+type Logger interface {
+	Log(ctx context.Context, r Record)
+}
+
+// LoggerFunc is an adapter to allow the use of ordinary functions as Logger.
+type LoggerFunc func(ctx context.Context, r Record)
+
+func (f LoggerFunc) Log(ctx context.Context, r Record) {
+	f(ctx, r)
+}
+
+// StdLogger logs the records by the standard logger.
+type StdLogger struct {
+	// If Logger is nil, then log.Default() is used.
+	Logger *log.Logger
+}
+
+func (l StdLogger) Log(ctx context.Context, r Record) {
+	logger := l.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	logger.Print(r.String())
+}
+
+// DefaultRedactHeaders are always redacted by LoggingBuilder.
+var DefaultRedactHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+}
+
+const redacted = "REDACTED"
+
+type loggingKey struct{}
+
+type loggingState struct {
+	builder     LoggingBuilder
+	service     string
+	operation   string
+	requestBody string
+}
+
+// LoggingBuilder adds the logging options and a snapshot of the request body to the request context,
+// LoggingDeserializer logs a record for every attempt with them.
+//
+// LoggingBuilder should be added before request.RetryBuilder.
+// The request body is only logged if it implements io.Seeker.
+type LoggingBuilder struct {
+	// If Logger is nil, then StdLogger is used.
+	Logger Logger
+	// MaxBodySize is the max number of bytes of the request and response body to log.
+	// If MaxBodySize is 0, the bodies are not logged.
+	MaxBodySize int64
+	// RedactHeaders are the headers to redact, in addition to DefaultRedactHeaders.
+	RedactHeaders []string
+	// RedactJSONFields are the fields of JSON bodies to redact.
+	RedactJSONFields []string
+	// RequestIDHeader is the request header of the request id set by request.RequestIDBuilder,
+	// it is logged if RequestIDDeserializer doesn't get a request id from the response.
+	// default: X-Request-Id
+	RequestIDHeader string
+}
+
+func (b LoggingBuilder) ID() string { return "LoggingBuilder" }
+
+func (b LoggingBuilder) Builder(build httpc.BuildFunc) httpc.BuildFunc {
+	return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
+		return b.build(ctx, req, build)
+	}
+}
+
+func (b LoggingBuilder) requestIDHeader() string {
+	if b.RequestIDHeader == "" {
+		return "X-Request-Id"
+	}
+	return b.RequestIDHeader
+}
+
+func (b LoggingBuilder) logger() Logger {
+	if b.Logger == nil {
+		return StdLogger{}
+	}
+	return b.Logger
+}
+
+func (b LoggingBuilder) build(ctx context.Context, req *httpc.Request, build httpc.BuildFunc) (
+	output interface{}, md httpc.Metadata, err error,
+) {
+	state := &loggingState{
+		builder:   b,
+		service:   request.GetServiceNameFromContext(ctx),
+		operation: request.GetOperationNameFromContext(ctx),
+	}
+	if b.MaxBodySize > 0 && req.Body != nil && req.Body != http.NoBody {
+		var body []byte
+		rerr := request.ReadRewind(req.Body, func(r io.Reader) (err error) {
+			body, err = io.ReadAll(io.LimitReader(r, b.MaxBodySize+1))
+			return err
+		})
+		if rerr == nil {
+			state.requestBody = b.redactBody(req.Header, body)
+		}
+	}
+
+	req.Request = req.Request.WithContext(context.WithValue(req.Context(), loggingKey{}, state))
+	return build(ctx, req)
+}
+
+func (b LoggingBuilder) redactHeader(header http.Header) http.Header {
+	if header == nil {
+		return nil
+	}
+	header = header.Clone()
+	for _, keys := range [][]string{DefaultRedactHeaders, b.RedactHeaders} {
+		for _, key := range keys {
+			if _, ok := header[http.CanonicalHeaderKey(key)]; ok {
+				header.Set(key, redacted)
+			}
+		}
+	}
+	return header
+}
+
+// redactBody redacts the JSON fields of body and truncates it to MaxBodySize,
+// body may be read with an extra byte to detect truncation.
+func (b LoggingBuilder) redactBody(header http.Header, body []byte) string {
+	truncated := int64(len(body)) > b.MaxBodySize
+	if truncated {
+		body = body[:b.MaxBodySize]
+	}
+	if len(b.RedactJSONFields) > 0 && strings.Contains(header.Get("Content-Type"), "json") {
+		body = b.redactJSON(body, truncated)
+	}
+	s := string(body)
+	if truncated {
+		s += "...(truncated)"
+	}
+	return s
+}
+
+func (b LoggingBuilder) isRedactField(field string) bool {
+	for _, f := range b.RedactJSONFields {
+		if strings.EqualFold(f, field) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b LoggingBuilder) redactJSONValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if b.isRedactField(key) {
+				v[key] = redacted
+			} else {
+				v[key] = b.redactJSONValue(value)
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = b.redactJSONValue(value)
+		}
+	}
+	return v
+}
+
+// redactJSON redacts a complete JSON body by decoding it,
+// or the string fields of a truncated JSON body by pattern.
+func (b LoggingBuilder) redactJSON(body []byte, truncated bool) []byte {
+	if !truncated {
+		var v interface{}
+		d := json.NewDecoder(bytes.NewReader(body))
+		d.UseNumber()
+		if err := d.Decode(&v); err == nil {
+			if out, err := json.Marshal(b.redactJSONValue(v)); err == nil {
+				return out
+			}
+		}
+	}
+	for _, field := range b.RedactJSONFields {
+		body = redactFieldPattern(field).ReplaceAll(body, []byte(`${1}"`+redacted+`"`))
+	}
+	return body
+}
+
+// redactFieldPatterns caches the patterns of redactFieldPattern by field.
+var redactFieldPatterns sync.Map
+
+// redactFieldPattern returns the pattern matching the string field of a truncated JSON body.
+func redactFieldPattern(field string) *regexp.Regexp {
+	if re, ok := redactFieldPatterns.Load(field); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(`(?i)("` + regexp.QuoteMeta(field) + `"\s*:\s*)("(?:[^"\\]|\\.)*("|$)|[^,}\]\s]+)`)
+	actual, _ := redactFieldPatterns.LoadOrStore(field, re)
+	return actual.(*regexp.Regexp)
+}
+
+func requestURL(req *http.Request) string {
+	if req.URL == nil {
+		return ""
+	}
+	u := *req.URL
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = nil
+		}
+	}
+	return u.String()
+}
+
+// LoggingDeserializer logs a record for every attempt with the options of LoggingBuilder,
+// the attempt is not logged without LoggingBuilder.
+//
+// LoggingDeserializer should be the last Deserializer except response.RequestIDDeserializer,
+// so that the response body is logged before it is consumed.
+// The request id is got from the metadata set by RequestIDDeserializer, which must be added after LoggingDeserializer,
+// or the request header LoggingBuilder.RequestIDHeader.
+// The response body is re-wrapped, so the later Deserializers read the full body.
+type LoggingDeserializer struct{}
+
+func (d LoggingDeserializer) ID() string { return "LoggingDeserializer" }
+
+func (d LoggingDeserializer) Deserializer(deserialize httpc.DeserializeFunc) httpc.DeserializeFunc {
+	return func(req *http.Request) (httpc.DeserializeOutput, httpc.Metadata, error) {
+		return d.deserialize(req, deserialize)
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func (d LoggingDeserializer) deserialize(req *http.Request, deserialize httpc.DeserializeFunc) (
+	output httpc.DeserializeOutput, md httpc.Metadata, err error,
+) {
+	state, _ := req.Context().Value(loggingKey{}).(*loggingState)
+	if state == nil {
+		return deserialize(req)
+	}
+
+	b := state.builder
+	start := time.Now()
+	output, md, err = deserialize(req)
+
+	attempt, _ := request.GetAttemptFromContext(req.Context())
+	if attempt == 0 {
+		attempt = 1
+	}
+	r := Record{
+		Time:          start,
+		Service:       state.service,
+		Operation:     state.operation,
+		Attempt:       attempt,
+		Method:        req.Method,
+		URL:           requestURL(req),
+		RequestID:     response.GetRequestID(md),
+		RequestHeader: b.redactHeader(req.Header),
+		RequestBody:   state.requestBody,
+		Latency:       time.Since(start),
+		Err:           err,
+	}
+	if r.RequestID == "" {
+		r.RequestID = req.Header.Get(b.requestIDHeader())
+	}
+	if r.Method == "" {
+		r.Method = http.MethodGet
+	}
+	if resp := output.Response; resp != nil {
+		r.StatusCode = resp.StatusCode
+		r.ResponseHeader = b.redactHeader(resp.Header)
+		if b.MaxBodySize > 0 && resp.Body != nil && resp.Body != http.NoBody {
+			body, rerr := io.ReadAll(io.LimitReader(resp.Body, b.MaxBodySize+1))
+			resp.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), Closer: resp.Body}
+			if rerr != nil {
+				r.ResponseBody = fmt.Sprintf("(read body failed, %v)", rerr)
+			} else {
+				r.ResponseBody = b.redactBody(resp.Header, body)
+			}
+		}
+	}
+	b.logger().Log(req.Context(), r)
+	return
+}
@@ -0,0 +1,190 @@
+package httplog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-camp/httpc"
+	"github.com/go-camp/httpc/request"
+	"github.com/go-camp/httpc/response"
+)
+
+func TestLogging(t *testing.T) {
+	var records []Record
+	var bodies []string
+	h := httpc.Handler{
+		Initializer: request.ServiceOperationNameInitializer{
+			ServiceName:   "example",
+			OperationName: "Login",
+		}.Initializer,
+		Serializer: func(serialize httpc.SerializeFunc) httpc.SerializeFunc {
+			return func(ctx context.Context, input httpc.SerializeInput) (output interface{}, md httpc.Metadata, err error) {
+				input.Request = &httpc.Request{
+					Request: &http.Request{
+						Method: http.MethodPost,
+						URL:    &url.URL{Scheme: "https", Host: "example.com", Path: "/login"},
+						Header: http.Header{
+							"Authorization": {"Bearer secret"},
+							"Content-Type":  {"application/json"},
+							"X-Api-Key":     {"key"},
+						},
+					},
+					Body: strings.NewReader(`{"user":"name","password":"secret"}`),
+				}
+				return serialize(ctx, input)
+			}
+		},
+		Builder: httpc.ComposeBuilder(
+			LoggingBuilder{
+				Logger:           LoggerFunc(func(ctx context.Context, r Record) { records = append(records, r) }),
+				MaxBodySize:      1024,
+				RedactHeaders:    []string{"X-Api-Key"},
+				RedactJSONFields: []string{"password", "token"},
+				RequestIDHeader:  "X-Client-Id",
+			}.Builder,
+			request.RequestIDBuilder{
+				Header:      "X-Client-Id",
+				IDGenerator: func() (string, error) { return "client-id", nil },
+			}.Builder,
+			request.RetryBuilder{
+				Retryer: request.BasicRetryer{
+					Options: request.BasicRetryerOptions{Delayer: request.NopRetryDelayer},
+				},
+			}.Builder,
+		),
+		Deserializer: httpc.ComposeDeserializer(
+			func(deserialize httpc.DeserializeFunc) httpc.DeserializeFunc {
+				return func(req *http.Request) (output httpc.DeserializeOutput, md httpc.Metadata, err error) {
+					output, md, err = deserialize(req)
+					if err != nil {
+						return
+					}
+					body, _ := io.ReadAll(output.Response.Body)
+					bodies = append(bodies, string(body))
+					if output.Response.StatusCode != http.StatusOK {
+						err = &httpc.ResponseError{Response: output.Response, Err: io.ErrUnexpectedEOF}
+					}
+					return
+				}
+			},
+			LoggingDeserializer{}.Deserializer,
+			response.RequestIDDeserializer{Headers: []string{"X-Amzn-Requestid"}}.Deserializer,
+		),
+		Do: func(req *http.Request) (*http.Response, error) {
+			io.Copy(io.Discard, req.Body)
+			resp := &http.Response{
+				StatusCode: http.StatusOK,
+				Header: http.Header{
+					"Content-Type": {"application/json"},
+					"Set-Cookie":   {"session=secret"},
+				},
+				Body: io.NopCloser(strings.NewReader(`{"token":"secret","expires":3600}`)),
+			}
+			if len(bodies) == 0 {
+				resp.StatusCode = http.StatusServiceUnavailable
+			} else {
+				resp.Header.Set("X-Amzn-Requestid", "server-id")
+			}
+			return resp, nil
+		},
+	}
+
+	_, _, err := h.Handle(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("expect no err, got %v", err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("expect 2 records, got %d", len(records))
+	}
+	for i, r := range records {
+		if r.Attempt != i+1 || r.Service != "example" || r.Operation != "Login" ||
+			r.Method != http.MethodPost || r.URL != "https://example.com/login" {
+			t.Fatalf("unexpected record %d %+v", i+1, r)
+		}
+		if r.RequestHeader.Get("Authorization") != "REDACTED" || r.RequestHeader.Get("X-Api-Key") != "REDACTED" ||
+			r.ResponseHeader.Get("Set-Cookie") != "REDACTED" {
+			t.Fatalf("expect headers are redacted, got %v %v", r.RequestHeader, r.ResponseHeader)
+		}
+		if r.RequestBody != `{"password":"REDACTED","user":"name"}` {
+			t.Fatalf("unexpected request body %s", r.RequestBody)
+		}
+		if r.ResponseBody != `{"expires":3600,"token":"REDACTED"}` {
+			t.Fatalf("unexpected response body %s", r.ResponseBody)
+		}
+		if bodies[i] != `{"token":"secret","expires":3600}` {
+			t.Fatalf("expect response body is not consumed, got %s", bodies[i])
+		}
+	}
+	if records[0].RequestID != "client-id" || records[1].RequestID != "server-id" {
+		t.Fatalf("unexpected request ids %s %s", records[0].RequestID, records[1].RequestID)
+	}
+	if records[0].StatusCode != http.StatusServiceUnavailable || records[1].StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status codes %d %d", records[0].StatusCode, records[1].StatusCode)
+	}
+}
+
+func TestLoggingBuilderRedactBody(t *testing.T) {
+	testCases := []struct {
+		Name        string
+		MaxBodySize int64
+		Body        string
+
+		ExpectBody string
+	}{
+		{
+			Name:        "complete",
+			MaxBodySize: 100,
+			Body:        `{"a":{"password":"p"},"b":[{"password":1}]}`,
+			ExpectBody:  `{"a":{"password":"REDACTED"},"b":[{"password":"REDACTED"}]}`,
+		},
+		{
+			Name:        "truncated",
+			MaxBodySize: 30,
+			Body:        `{"password":"p\"q","user":"name","other":"value"}`,
+			ExpectBody:  `{"password":"REDACTED","user":"nam...(truncated)`,
+		},
+		{
+			Name:        "truncated_in_field",
+			MaxBodySize: 16,
+			Body:        `{"password":"secret"}`,
+			ExpectBody:  `{"password":"REDACTED"...(truncated)`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			b := LoggingBuilder{MaxBodySize: tc.MaxBodySize, RedactJSONFields: []string{"password"}}
+			header := http.Header{"Content-Type": {"application/json"}}
+			body := []byte(tc.Body)
+			if int64(len(body)) > tc.MaxBodySize+1 {
+				body = body[:tc.MaxBodySize+1]
+			}
+			if s := b.redactBody(header, body); s != tc.ExpectBody {
+				t.Fatalf("expect body is %s, got %s", tc.ExpectBody, s)
+			}
+		})
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	StdLogger{Logger: log.New(&buf, "", 0)}.Log(context.Background(), Record{
+		Service:    "example",
+		Operation:  "Get",
+		Attempt:    1,
+		Method:     http.MethodGet,
+		URL:        "https://example.com/items?q=a b",
+		StatusCode: http.StatusOK,
+	})
+	expect := `service=example operation=Get attempt=1 method=GET url="https://example.com/items?q=a b" status=200 latency=0s` + "\n"
+	if buf.String() != expect {
+		t.Fatalf("expect log is %s, got %s", expect, buf.String())
+	}
+}
@@ -0,0 +1,107 @@
+// Package metrics provides the metrics middleware of httpc,
+// and a collector with the Prometheus text exposition format.
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-camp/httpc"
+	"github.com/go-camp/httpc/request"
+)
+
+// Observation is the result of an attempt.
+type Observation struct {
+	Service   string
+	Operation string
+	// Attempt is the attempt number starting from 1.
+	Attempt int
+	// StatusCode is the response status code, 0 if there is no response.
+	StatusCode int
+	// Fault is the ErrorFault of the APIError, ErrorFaultUnknown if err is not an APIError.
+	Fault   httpc.ErrorFault
+	Err     error
+	Latency time.Duration
+}
+
+// StatusClass returns the status class of the response such as "2xx",
+// or "none" if there is no response.
+func (o Observation) StatusClass() string {
+	if o.StatusCode < 100 || o.StatusCode > 999 {
+		return "none"
+	}
+	return strconv.Itoa(o.StatusCode/100) + "xx"
+}
+
+// FaultLabel returns the lower case ErrorFault if Err is not nil, otherwise an empty string.
+func (o Observation) FaultLabel() string {
+	if o.Err == nil {
+		return ""
+	}
+	return strings.ToLower(o.Fault.String())
+}
+
+// Collector collects the metrics of attempts.
+type Collector interface {
+	// Start is called before the request is sent.
+	Start(service, operation string)
+	// Observe is called after the response is deserialized.
+	Observe(o Observation)
+}
+
+// MetricsDeserializer reports every attempt to Collector.
+//
+// MetricsDeserializer should be the first Deserializer, so that the APIError is observed.
+// The service and operation names are got from the request context,
+// so the request must be created with the context of the call.
+type MetricsDeserializer struct {
+	Collector Collector
+}
+
+func (d MetricsDeserializer) ID() string { return "MetricsDeserializer" }
+
+func (d MetricsDeserializer) Deserializer(deserialize httpc.DeserializeFunc) httpc.DeserializeFunc {
+	return func(req *http.Request) (httpc.DeserializeOutput, httpc.Metadata, error) {
+		return d.deserialize(req, deserialize)
+	}
+}
+
+func (d MetricsDeserializer) deserialize(req *http.Request, deserialize httpc.DeserializeFunc) (
+	output httpc.DeserializeOutput, md httpc.Metadata, err error,
+) {
+	if d.Collector == nil {
+		return deserialize(req)
+	}
+
+	ctx := req.Context()
+	o := Observation{
+		Service:   request.GetServiceNameFromContext(ctx),
+		Operation: request.GetOperationNameFromContext(ctx),
+	}
+	o.Attempt, _ = request.GetAttemptFromContext(ctx)
+	if o.Attempt == 0 {
+		o.Attempt = 1
+	}
+
+	d.Collector.Start(o.Service, o.Operation)
+	start := time.Now()
+	output, md, err = deserialize(req)
+	o.Latency = time.Since(start)
+	o.Err = err
+
+	var statusErr interface{ HTTPStatusCode() int }
+	if output.Response != nil {
+		o.StatusCode = output.Response.StatusCode
+	} else if errors.As(err, &statusErr) {
+		o.StatusCode = statusErr.HTTPStatusCode()
+	}
+	var apiErr httpc.APIError
+	if errors.As(err, &apiErr) {
+		o.Fault = apiErr.ErrorFault()
+	}
+	d.Collector.Observe(o)
+	return
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-camp/httpc"
+	"github.com/go-camp/httpc/request"
+)
+
+type testCollector struct {
+	started      []string
+	observations []Observation
+}
+
+func (c *testCollector) Start(service, operation string) {
+	c.started = append(c.started, service+"/"+operation)
+}
+
+func (c *testCollector) Observe(o Observation) {
+	c.observations = append(c.observations, o)
+}
+
+func TestMetricsDeserializer(t *testing.T) {
+	testCases := []struct {
+		Name       string
+		StatusCode int
+		Err        error
+
+		ExpectStatusClass string
+		ExpectFault       string
+	}{
+		{
+			Name:              "success",
+			StatusCode:        http.StatusOK,
+			ExpectStatusClass: "2xx",
+		},
+		{
+			Name:              "client_fault",
+			StatusCode:        http.StatusBadRequest,
+			Err:               &httpc.GenericAPIError{Code: "InvalidParameter", Fault: httpc.ErrorFaultClient},
+			ExpectStatusClass: "4xx",
+			ExpectFault:       "client",
+		},
+		{
+			Name:              "send_error",
+			Err:               &httpc.RequestSendError{Err: errors.New("connection refused")},
+			ExpectStatusClass: "none",
+			ExpectFault:       "unknown",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			collector := &testCollector{}
+			deserialize := MetricsDeserializer{Collector: collector}.Deserializer(
+				func(req *http.Request) (output httpc.DeserializeOutput, md httpc.Metadata, err error) {
+					if tc.StatusCode > 0 {
+						output.Response = &http.Response{StatusCode: tc.StatusCode}
+					}
+					return output, md, tc.Err
+				},
+			)
+
+			ctx := context.Background()
+			ini := request.ServiceOperationNameInitializer{ServiceName: "example", OperationName: "Get"}.Initializer(
+				func(c context.Context, input interface{}) (output interface{}, md httpc.Metadata, err error) {
+					ctx = c
+					return
+				},
+			)
+			ini(ctx, nil)
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+			deserialize(req)
+
+			if len(collector.started) != 1 || collector.started[0] != "example/Get" {
+				t.Fatalf("unexpected started %v", collector.started)
+			}
+			if len(collector.observations) != 1 {
+				t.Fatalf("expect 1 observation, got %d", len(collector.observations))
+			}
+			o := collector.observations[0]
+			if o.Service != "example" || o.Operation != "Get" || o.Attempt != 1 {
+				t.Fatalf("unexpected observation %+v", o)
+			}
+			if o.StatusClass() != tc.ExpectStatusClass {
+				t.Fatalf("expect status class is %s, got %s", tc.ExpectStatusClass, o.StatusClass())
+			}
+			if o.FaultLabel() != tc.ExpectFault {
+				t.Fatalf("expect fault is %q, got %q", tc.ExpectFault, o.FaultLabel())
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package metrics
+
+import (
+	"bufio"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the default latency histogram buckets in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+const (
+	metricRequests = "httpc_client_requests_total"
+	metricDuration = "httpc_client_request_duration_seconds"
+	metricRetries  = "httpc_client_retries_total"
+	metricInFlight = "httpc_client_requests_in_flight"
+)
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// PrometheusCollector is a Collector which exposes the metrics in the Prometheus text format.
+//
+// The metrics are:
+// httpc_client_requests_total counter by service, operation, status_class and fault,
+// httpc_client_request_duration_seconds histogram by service and operation,
+// httpc_client_retries_total counter by service and operation,
+// and httpc_client_requests_in_flight gauge by service and operation.
+type PrometheusCollector struct {
+	// Buckets are the upper bounds of latency histogram buckets in seconds, in increasing order.
+	// If Buckets is nil, then DefaultBuckets is used.
+	Buckets []float64
+
+	mux      sync.Mutex
+	requests map[[4]string]uint64
+	duration map[[2]string]*histogram
+	retries  map[[2]string]uint64
+	inFlight map[[2]string]int64
+}
+
+var _ Collector = (*PrometheusCollector)(nil)
+
+func (c *PrometheusCollector) buckets() []float64 {
+	if c.Buckets == nil {
+		return DefaultBuckets
+	}
+	return c.Buckets
+}
+
+func (c *PrometheusCollector) init() {
+	if c.requests != nil {
+		return
+	}
+	c.requests = map[[4]string]uint64{}
+	c.duration = map[[2]string]*histogram{}
+	c.retries = map[[2]string]uint64{}
+	c.inFlight = map[[2]string]int64{}
+}
+
+func (c *PrometheusCollector) Start(service, operation string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.init()
+
+	c.inFlight[[2]string{service, operation}]++
+}
+
+func (c *PrometheusCollector) Observe(o Observation) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.init()
+
+	key := [2]string{o.Service, o.Operation}
+	c.inFlight[key]--
+	c.requests[[4]string{o.Service, o.Operation, o.StatusClass(), o.FaultLabel()}]++
+	if o.Attempt > 1 {
+		c.retries[key]++
+	}
+
+	h := c.duration[key]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(c.buckets()))}
+		c.duration[key] = h
+	}
+	seconds := o.Latency.Seconds()
+	for i, le := range c.buckets() {
+		if seconds <= le {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func writeLabels(w *bufio.Writer, names []string, values []string) {
+	w.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		w.WriteString(name)
+		w.WriteString(`="`)
+		labelValueReplacer.WriteString(w, values[i])
+		w.WriteByte('"')
+	}
+	w.WriteByte('}')
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+func writeHeader(w *bufio.Writer, name, typ, help string) {
+	w.WriteString("# HELP " + name + " " + help + "\n")
+	w.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+func writeSample(w *bufio.Writer, name string, labelNames, labelValues []string, value string) {
+	w.WriteString(name)
+	writeLabels(w, labelNames, labelValues)
+	w.WriteByte(' ')
+	w.WriteString(value)
+	w.WriteByte('\n')
+}
+
+func sortedKeys2[V any](m map[[2]string]V) [][2]string {
+	keys := make([][2]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][0] < keys[j][0] || (keys[i][0] == keys[j][0] && keys[i][1] < keys[j][1])
+	})
+	return keys
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (c *PrometheusCollector) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w := bufio.NewWriter(rw)
+	defer w.Flush()
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	names := []string{"service", "operation"}
+
+	writeHeader(w, metricRequests, "counter", "Total number of httpc requests.")
+	requestKeys := make([][4]string, 0, len(c.requests))
+	for k := range c.requests {
+		requestKeys = append(requestKeys, k)
+	}
+	sort.Slice(requestKeys, func(i, j int) bool {
+		for n := 0; n < 4; n++ {
+			if requestKeys[i][n] != requestKeys[j][n] {
+				return requestKeys[i][n] < requestKeys[j][n]
+			}
+		}
+		return false
+	})
+	for _, k := range requestKeys {
+		writeSample(w, metricRequests, []string{"service", "operation", "status_class", "fault"}, k[:],
+			strconv.FormatUint(c.requests[k], 10))
+	}
+
+	writeHeader(w, metricDuration, "histogram", "Latency of httpc requests in seconds.")
+	for _, k := range sortedKeys2(c.duration) {
+		h := c.duration[k]
+		bucketNames := []string{"service", "operation", "le"}
+		for i, le := range c.buckets() {
+			writeSample(w, metricDuration+"_bucket", bucketNames, []string{k[0], k[1], formatFloat(le)},
+				strconv.FormatUint(h.counts[i], 10))
+		}
+		writeSample(w, metricDuration+"_bucket", bucketNames, []string{k[0], k[1], "+Inf"},
+			strconv.FormatUint(h.count, 10))
+		writeSample(w, metricDuration+"_sum", names, k[:], formatFloat(h.sum))
+		writeSample(w, metricDuration+"_count", names, k[:], strconv.FormatUint(h.count, 10))
+	}
+
+	writeHeader(w, metricRetries, "counter", "Total number of httpc retry attempts.")
+	for _, k := range sortedKeys2(c.retries) {
+		writeSample(w, metricRetries, names, k[:], strconv.FormatUint(c.retries[k], 10))
+	}
+
+	writeHeader(w, metricInFlight, "gauge", "Number of httpc requests in flight.")
+	for _, k := range sortedKeys2(c.inFlight) {
+		writeSample(w, metricInFlight, names, k[:], strconv.FormatInt(c.inFlight[k], 10))
+	}
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-camp/httpc"
+)
+
+func TestPrometheusCollector(t *testing.T) {
+	c := &PrometheusCollector{Buckets: []float64{0.1, 1}}
+	c.Start("example", "Get")
+	c.Observe(Observation{
+		Service:    "example",
+		Operation:  "Get",
+		Attempt:    1,
+		StatusCode: http.StatusServiceUnavailable,
+		Fault:      httpc.ErrorFaultServer,
+		Err:        &httpc.GenericAPIError{Fault: httpc.ErrorFaultServer},
+		Latency:    50 * time.Millisecond,
+	})
+	c.Start("example", "Get")
+	c.Observe(Observation{
+		Service:    "example",
+		Operation:  "Get",
+		Attempt:    2,
+		StatusCode: http.StatusOK,
+		Latency:    500 * time.Millisecond,
+	})
+	c.Start("example", "Get")
+	c.Start(`quote"d`, "Put")
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	expect := `# HELP httpc_client_requests_total Total number of httpc requests.
+# TYPE httpc_client_requests_total counter
+httpc_client_requests_total{service="example",operation="Get",status_class="2xx",fault=""} 1
+httpc_client_requests_total{service="example",operation="Get",status_class="5xx",fault="server"} 1
+# HELP httpc_client_request_duration_seconds Latency of httpc requests in seconds.
+# TYPE httpc_client_request_duration_seconds histogram
+httpc_client_request_duration_seconds_bucket{service="example",operation="Get",le="0.1"} 1
+httpc_client_request_duration_seconds_bucket{service="example",operation="Get",le="1"} 2
+httpc_client_request_duration_seconds_bucket{service="example",operation="Get",le="+Inf"} 2
+httpc_client_request_duration_seconds_sum{service="example",operation="Get"} 0.55
+httpc_client_request_duration_seconds_count{service="example",operation="Get"} 2
+# HELP httpc_client_retries_total Total number of httpc retry attempts.
+# TYPE httpc_client_retries_total counter
+httpc_client_retries_total{service="example",operation="Get"} 1
+# HELP httpc_client_requests_in_flight Number of httpc requests in flight.
+# TYPE httpc_client_requests_in_flight gauge
+httpc_client_requests_in_flight{service="example",operation="Get"} 1
+httpc_client_requests_in_flight{service="quote\"d",operation="Put"} 1
+`
+	if rec.Body.String() != expect {
+		t.Fatalf("expect metrics are:\n%s\ngot:\n%s", expect, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Fatalf("unexpected content type %s", ct)
+	}
+}
@@ -0,0 +1,27 @@
+module github.com/go-camp/httpc/otelhttpc
+
+// The OpenTelemetry Go modules require go 1.21,
+// the root module keeps supporting go 1.18.
+go 1.21
+
+// The replace directive only applies when developing in this repository,
+// dependents use the required version of github.com/go-camp/httpc,
+// so the root module is tagged v0.1.0 before otelhttpc/v0.1.0 is released.
+replace github.com/go-camp/httpc => ../
+
+require (
+	github.com/go-camp/httpc v0.1.0
+	go.opentelemetry.io/otel v1.28.0
+	go.opentelemetry.io/otel/metric v1.28.0
+	go.opentelemetry.io/otel/sdk v1.28.0
+	go.opentelemetry.io/otel/sdk/metric v1.28.0
+	go.opentelemetry.io/otel/trace v1.28.0
+)
+
+require (
+	github.com/go-camp/retry v0.0.0-20210813070521-91835365fb35 // indirect
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+)
@@ -0,0 +1,327 @@
+// Package otelhttpc provides OpenTelemetry instrumentation for httpc.
+//
+// OperationInitializer starts a span for every call and records the call metrics,
+// PropagationBuilder sets the HTTP attributes of the call and injects the propagation headers,
+// AttemptFinalizer starts a client span for every attempt of request.RetryBuilder,
+// and StatusDeserializer records the response status of the attempt.
+package otelhttpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-camp/httpc"
+	"github.com/go-camp/httpc/request"
+	"github.com/go-camp/httpc/response"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope name of the tracers and meters.
+const ScopeName = "github.com/go-camp/httpc/otelhttpc"
+
+const (
+	// ErrorCodeKey is the attribute key of APIError.ErrorCode.
+	ErrorCodeKey = attribute.Key("httpc.error.code")
+	// ErrorFaultKey is the attribute key of APIError.ErrorFault.
+	ErrorFaultKey = attribute.Key("httpc.error.fault")
+)
+
+const (
+	metricOperationDuration = "httpc.client.operation.duration"
+	metricOperationAttempts = "httpc.client.operation.attempts"
+)
+
+func tracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(ScopeName)
+}
+
+func propagators(p propagation.TextMapPropagator) propagation.TextMapPropagator {
+	if p == nil {
+		return otel.GetTextMapPropagator()
+	}
+	return p
+}
+
+func statusCode(md httpc.Metadata, err error) int {
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) {
+		return statusErr.HTTPStatusCode()
+	}
+	if resp := response.GetResponse(md); resp != nil {
+		return resp.StatusCode
+	}
+	return 0
+}
+
+// errorType returns the APIError code, the status code or "_OTHER".
+func errorType(err error, code int) string {
+	var apiErr httpc.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() != "" {
+		return apiErr.ErrorCode()
+	}
+	if code >= 400 {
+		return strconv.Itoa(code)
+	}
+	return semconv.ErrorTypeOther.Value.AsString()
+}
+
+func errorAttributes(err error, code int) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	var apiErr httpc.APIError
+	if errors.As(err, &apiErr) {
+		attrs = append(attrs,
+			ErrorCodeKey.String(apiErr.ErrorCode()),
+			ErrorFaultKey.String(strings.ToLower(apiErr.ErrorFault().String())),
+		)
+	}
+	return append(attrs, semconv.ErrorTypeKey.String(errorType(err, code)))
+}
+
+func endSpan(span trace.Span, md httpc.Metadata, err error) {
+	code := statusCode(md, err)
+	if code > 0 {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(code))
+	}
+	if err != nil {
+		span.SetAttributes(errorAttributes(err, code)...)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// OperationInitializer starts a span for every call,
+// and records the duration and the number of attempts of every call.
+//
+// OperationInitializer must be added after request.ServiceOperationNameInitializer,
+// the span is named "service/operation".
+// The metric instruments are created on the first call,
+// so an OperationInitializer is usually shared by the calls of a client and must not be copied after first use.
+type OperationInitializer struct {
+	// If TracerProvider is nil, then the global TracerProvider is used.
+	TracerProvider trace.TracerProvider
+	// If MeterProvider is nil, then the global MeterProvider is used.
+	MeterProvider metric.MeterProvider
+
+	once sync.Once
+	ins  operationInstruments
+}
+
+func (ini *OperationInitializer) ID() string { return "OperationInitializer" }
+
+func (ini *OperationInitializer) Initializer(initialize httpc.InitializeFunc) httpc.InitializeFunc {
+	return func(ctx context.Context, input interface{}) (interface{}, httpc.Metadata, error) {
+		return ini.initialize(ctx, input, initialize)
+	}
+}
+
+type operationInstruments struct {
+	duration metric.Float64Histogram
+	attempts metric.Int64Histogram
+}
+
+func (ini *OperationInitializer) instruments() *operationInstruments {
+	ini.once.Do(func() {
+		mp := ini.MeterProvider
+		if mp == nil {
+			mp = otel.GetMeterProvider()
+		}
+		meter := mp.Meter(ScopeName)
+		ini.ins.duration, _ = meter.Float64Histogram(metricOperationDuration,
+			metric.WithUnit("s"),
+			metric.WithDescription("Duration of httpc operations."),
+		)
+		ini.ins.attempts, _ = meter.Int64Histogram(metricOperationAttempts,
+			metric.WithUnit("{attempt}"),
+			metric.WithDescription("Number of attempts of httpc operations."),
+		)
+	})
+	return &ini.ins
+}
+
+func (ini *OperationInitializer) initialize(ctx context.Context, input interface{}, initialize httpc.InitializeFunc) (
+	output interface{}, md httpc.Metadata, err error,
+) {
+	serviceName := request.GetServiceNameFromContext(ctx)
+	operationName := request.GetOperationNameFromContext(ctx)
+	attrs := []attribute.KeyValue{
+		semconv.RPCService(serviceName),
+		semconv.RPCMethod(operationName),
+	}
+
+	start := time.Now()
+	ctx, span := tracer(ini.TracerProvider).Start(ctx, serviceName+"/"+operationName,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attrs...),
+	)
+	defer func() {
+		attempts := len(request.GetAttempts(md).Attempts)
+		if attempts > 1 {
+			span.SetAttributes(semconv.HTTPRequestResendCount(attempts - 1))
+		}
+		endSpan(span, md, err)
+
+		if attempts == 0 {
+			attempts = 1
+		}
+		code := statusCode(md, err)
+		if code > 0 {
+			attrs = append(attrs, semconv.HTTPResponseStatusCode(code))
+		}
+		if err != nil {
+			var apiErr httpc.APIError
+			if errors.As(err, &apiErr) {
+				attrs = append(attrs, ErrorFaultKey.String(strings.ToLower(apiErr.ErrorFault().String())))
+			}
+			attrs = append(attrs, semconv.ErrorTypeKey.String(errorType(err, code)))
+		}
+		ini.record(ctx, time.Since(start), attempts, attrs)
+	}()
+
+	return initialize(ctx, input)
+}
+
+func (ini *OperationInitializer) record(ctx context.Context, duration time.Duration, attempts int, attrs []attribute.KeyValue) {
+	ins := ini.instruments()
+	opt := metric.WithAttributes(attrs...)
+	if ins.duration != nil {
+		ins.duration.Record(ctx, duration.Seconds(), opt)
+	}
+	if ins.attempts != nil {
+		ins.attempts.Record(ctx, int64(attempts), opt)
+	}
+}
+
+func requestAttributes(req *http.Request) []attribute.KeyValue {
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	attrs := []attribute.KeyValue{semconv.HTTPRequestMethodKey.String(method)}
+	if req.URL == nil {
+		return attrs
+	}
+	u := *req.URL
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = nil
+		}
+	}
+	attrs = append(attrs, semconv.URLFull(u.String()))
+	host := u.Host
+	if host == "" {
+		host = req.Host
+	}
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		host = h
+		if port, err := strconv.Atoi(p); err == nil {
+			attrs = append(attrs, semconv.ServerPort(port))
+		}
+	}
+	if host != "" {
+		attrs = append(attrs, semconv.ServerAddress(host))
+	}
+	return attrs
+}
+
+// PropagationBuilder sets the HTTP attributes of the request to the span in context,
+// and injects the span in context into the request headers.
+type PropagationBuilder struct {
+	// If Propagators is nil, then the global TextMapPropagator is used.
+	Propagators propagation.TextMapPropagator
+}
+
+func (b PropagationBuilder) ID() string { return "PropagationBuilder" }
+
+func (b PropagationBuilder) Builder(build httpc.BuildFunc) httpc.BuildFunc {
+	return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
+		trace.SpanFromContext(ctx).SetAttributes(requestAttributes(req.Request)...)
+		propagators(b.Propagators).Inject(ctx, propagation.HeaderCarrier(req.Header))
+		return build(ctx, req)
+	}
+}
+
+// AttemptFinalizer starts a client span for every attempt,
+// and injects the span into the request headers.
+type AttemptFinalizer struct {
+	// If TracerProvider is nil, then the global TracerProvider is used.
+	TracerProvider trace.TracerProvider
+	// If Propagators is nil, then the global TextMapPropagator is used.
+	Propagators propagation.TextMapPropagator
+}
+
+func (f AttemptFinalizer) ID() string { return "AttemptFinalizer" }
+
+func (f AttemptFinalizer) Finalizer(finalize httpc.FinalizeFunc) httpc.FinalizeFunc {
+	return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
+		return f.finalize(ctx, req, finalize)
+	}
+}
+
+func (f AttemptFinalizer) finalize(ctx context.Context, req *httpc.Request, finalize httpc.FinalizeFunc) (
+	output interface{}, md httpc.Metadata, err error,
+) {
+	attrs := requestAttributes(req.Request)
+	if attempt, _ := request.GetAttemptFromContext(ctx); attempt > 1 {
+		attrs = append(attrs, semconv.HTTPRequestResendCount(attempt-1))
+	}
+	method := attrs[0].Value.AsString()
+
+	var span trace.Span
+	ctx, span = tracer(f.TracerProvider).Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	defer func() {
+		endSpan(span, md, err)
+	}()
+
+	propagators(f.Propagators).Inject(ctx, propagation.HeaderCarrier(req.Header))
+	req.Request = req.Request.WithContext(trace.ContextWithSpan(req.Context(), span))
+
+	return finalize(ctx, req)
+}
+
+// StatusDeserializer records the response status to the span in the request context,
+// which is the attempt span of AttemptFinalizer.
+// The span status is set to error for 4xx and 5xx responses.
+type StatusDeserializer struct{}
+
+func (d StatusDeserializer) ID() string { return "StatusDeserializer" }
+
+func (d StatusDeserializer) Deserializer(deserialize httpc.DeserializeFunc) httpc.DeserializeFunc {
+	return func(req *http.Request) (httpc.DeserializeOutput, httpc.Metadata, error) {
+		return d.deserialize(req, deserialize)
+	}
+}
+
+func (d StatusDeserializer) deserialize(req *http.Request, deserialize httpc.DeserializeFunc) (
+	output httpc.DeserializeOutput, md httpc.Metadata, err error,
+) {
+	output, md, err = deserialize(req)
+	resp := output.Response
+	if resp == nil {
+		return
+	}
+	span := trace.SpanFromContext(req.Context())
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	return
+}
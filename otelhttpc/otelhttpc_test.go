@@ -0,0 +1,159 @@
+package otelhttpc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/go-camp/httpc"
+	"github.com/go-camp/httpc/request"
+	"github.com/go-camp/httpc/response"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestInstrumentation(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	propagators := propagation.TraceContext{}
+
+	var traceparents []string
+	h := httpc.Handler{
+		Initializer: httpc.ComposeInitializer(
+			request.ServiceOperationNameInitializer{
+				ServiceName:   "example",
+				OperationName: "GetItem",
+			}.Initializer,
+			(&OperationInitializer{TracerProvider: tp, MeterProvider: mp}).Initializer,
+		),
+		Serializer: func(serialize httpc.SerializeFunc) httpc.SerializeFunc {
+			return func(ctx context.Context, input httpc.SerializeInput) (output interface{}, md httpc.Metadata, err error) {
+				input.Request = &httpc.Request{
+					Request: (&http.Request{
+						Method: http.MethodGet,
+						URL:    &url.URL{Scheme: "http", Host: "example.com:8080", Path: "/items/1"},
+						Header: http.Header{},
+					}).WithContext(ctx),
+				}
+				return serialize(ctx, input)
+			}
+		},
+		Builder: httpc.ComposeBuilder(
+			PropagationBuilder{Propagators: propagators}.Builder,
+			request.RetryBuilder{
+				Retryer: request.BasicRetryer{
+					Options: request.BasicRetryerOptions{Delayer: request.NopRetryDelayer},
+				},
+			}.Builder,
+		),
+		Finalizer: AttemptFinalizer{TracerProvider: tp, Propagators: propagators}.Finalizer,
+		Deserializer: httpc.ComposeDeserializer(
+			response.WrapResponseErrorDeserializer{}.Deserializer,
+			response.ResponseDeserializer{}.Deserializer,
+			StatusDeserializer{}.Deserializer,
+			func(deserialize httpc.DeserializeFunc) httpc.DeserializeFunc {
+				return func(req *http.Request) (output httpc.DeserializeOutput, md httpc.Metadata, err error) {
+					output, md, err = deserialize(req)
+					if err == nil && output.Response.StatusCode != http.StatusOK {
+						err = &httpc.GenericAPIError{Code: "ServiceUnavailable", Fault: httpc.ErrorFaultServer}
+					}
+					return
+				}
+			},
+		),
+		Do: func(req *http.Request) (*http.Response, error) {
+			traceparents = append(traceparents, req.Header.Get("traceparent"))
+			statusCode := http.StatusOK
+			if len(traceparents) == 1 {
+				statusCode = http.StatusServiceUnavailable
+			}
+			return &http.Response{StatusCode: statusCode, Header: http.Header{}, Body: http.NoBody}, nil
+		},
+	}
+
+	_, _, err := h.Handle(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("expect no err, got %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expect 3 spans, got %d", len(spans))
+	}
+	attempts, op := spans[:2], spans[2]
+	if op.Name() != "example/GetItem" || op.SpanKind() != trace.SpanKindInternal || op.Status().Code == codes.Error {
+		t.Fatalf("unexpected operation span %s %s %v", op.Name(), op.SpanKind(), op.Status())
+	}
+	if v, _ := spanAttribute(op, "http.request.resend_count"); v.AsInt64() != 1 {
+		t.Fatalf("expect resend count of operation span is 1, got %v", v.AsInt64())
+	}
+	if v, _ := spanAttribute(op, "server.address"); v.AsString() != "example.com" {
+		t.Fatalf("expect server address is example.com, got %s", v.AsString())
+	}
+
+	for i, span := range attempts {
+		if span.Name() != http.MethodGet || span.SpanKind() != trace.SpanKindClient {
+			t.Fatalf("unexpected attempt span %s %s", span.Name(), span.SpanKind())
+		}
+		if span.Parent().SpanID() != op.SpanContext().SpanID() {
+			t.Fatalf("expect attempt span %d is a child of operation span", i+1)
+		}
+		sc := trace.SpanContextFromContext(propagators.Extract(context.Background(),
+			propagation.HeaderCarrier{"Traceparent": []string{traceparents[i]}}))
+		if sc.SpanID() != span.SpanContext().SpanID() {
+			t.Fatalf("expect traceparent of attempt %d is attempt span, got %s", i+1, traceparents[i])
+		}
+	}
+	if attempts[0].Status().Code != codes.Error {
+		t.Fatalf("expect first attempt span status is error, got %v", attempts[0].Status())
+	}
+	if v, _ := spanAttribute(attempts[0], ErrorCodeKey); v.AsString() != "ServiceUnavailable" {
+		t.Fatalf("expect error code is ServiceUnavailable, got %s", v.AsString())
+	}
+	if v, _ := spanAttribute(attempts[0], ErrorFaultKey); v.AsString() != "server" {
+		t.Fatalf("expect error fault is server, got %s", v.AsString())
+	}
+	if v, _ := spanAttribute(attempts[1], "http.response.status_code"); v.AsInt64() != http.StatusOK {
+		t.Fatalf("expect status code of second attempt is 200, got %d", v.AsInt64())
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("expect no collect err, got %v", err)
+	}
+	metrics := map[string]metricdata.Aggregation{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+	duration, ok := metrics["httpc.client.operation.duration"].(metricdata.Histogram[float64])
+	if !ok || len(duration.DataPoints) != 1 || duration.DataPoints[0].Count != 1 {
+		t.Fatalf("unexpected duration metric %+v", metrics["httpc.client.operation.duration"])
+	}
+	attemptCount, ok := metrics["httpc.client.operation.attempts"].(metricdata.Histogram[int64])
+	if !ok || len(attemptCount.DataPoints) != 1 || attemptCount.DataPoints[0].Sum != 2 {
+		t.Fatalf("unexpected attempts metric %+v", metrics["httpc.client.operation.attempts"])
+	}
+	if v, _ := attemptCount.DataPoints[0].Attributes.Value("rpc.method"); v.AsString() != "GetItem" {
+		t.Fatalf("expect rpc.method of metric is GetItem, got %s", v.AsString())
+	}
+}
@@ -0,0 +1,118 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/go-camp/httpc"
+)
+
+type attemptKey struct{}
+
+type attemptValue struct {
+	attempt     int
+	maxAttempts int
+}
+
+func withAttempt(ctx context.Context, attempt, maxAttempts int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attemptValue{attempt: attempt, maxAttempts: maxAttempts})
+}
+
+// GetAttemptFromContext returns the attempt number starting from 1 and the maximum number of attempts,
+// which are set by RetryBuilder to the context and the request context of every attempt.
+// If the Retryer implements RequestRetryer, the maximum is the one for the error of the previous attempt.
+// The attempt is 0 if ctx is not from RetryBuilder.
+func GetAttemptFromContext(ctx context.Context) (attempt, maxAttempts int) {
+	v, _ := ctx.Value(attemptKey{}).(attemptValue)
+	return v.attempt, v.maxAttempts
+}
+
+// AttemptRequestIDFinalizer sets a new unique id to the value of specified request header for every attempt.
+//
+// To keep an id constant for all attempts of a call,
+// use RequestIDBuilder with another header, such as RequestIDBuilder{Header: "X-Operation-Id"}.
+type AttemptRequestIDFinalizer struct {
+	// default: X-Request-ID
+	Header      string
+	IDGenerator func() (string, error)
+}
+
+func (f AttemptRequestIDFinalizer) ID() string { return "AttemptRequestIDFinalizer" }
+
+func (f AttemptRequestIDFinalizer) Finalizer(finalize httpc.FinalizeFunc) httpc.FinalizeFunc {
+	return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
+		return f.finalize(ctx, req, finalize)
+	}
+}
+
+func (f AttemptRequestIDFinalizer) header() string {
+	if f.Header == "" {
+		return headerXRequestID
+	}
+	return f.Header
+}
+
+func (f AttemptRequestIDFinalizer) idGenerator() func() (string, error) {
+	if f.IDGenerator == nil {
+		return DefaultIDGenerator
+	}
+	return f.IDGenerator
+}
+
+func (f AttemptRequestIDFinalizer) finalize(ctx context.Context, req *httpc.Request, finalize httpc.FinalizeFunc) (
+	output interface{}, md httpc.Metadata, err error,
+) {
+	var id string
+	id, err = f.idGenerator()()
+	if err != nil {
+		err = &requestIDError{While: "attempt id generate", Err: err}
+		return
+	}
+	if id != "" {
+		req.Header.Set(f.header(), id)
+	}
+
+	return finalize(ctx, req)
+}
+
+// AttemptCountFinalizer sets the attempt number and the maximum number of attempts
+// to the value of specified request header, such as "attempt=2; max=3".
+//
+// The attempt is 1 and the maximum is omitted without RetryBuilder,
+// the maximum is also omitted if the number of attempts is unlimited.
+type AttemptCountFinalizer struct {
+	// default: X-Request-Attempt
+	Header string
+}
+
+func (f AttemptCountFinalizer) ID() string { return "AttemptCountFinalizer" }
+
+func (f AttemptCountFinalizer) Finalizer(finalize httpc.FinalizeFunc) httpc.FinalizeFunc {
+	return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
+		return f.finalize(ctx, req, finalize)
+	}
+}
+
+func (f AttemptCountFinalizer) header() string {
+	if f.Header == "" {
+		return headerXRequestAttempt
+	}
+	return f.Header
+}
+
+func (f AttemptCountFinalizer) finalize(ctx context.Context, req *httpc.Request, finalize httpc.FinalizeFunc) (
+	output interface{}, md httpc.Metadata, err error,
+) {
+	attempt, maxAttempts := GetAttemptFromContext(ctx)
+	if attempt <= 0 {
+		attempt = 1
+	}
+	v := "attempt=" + strconv.Itoa(attempt)
+	if maxAttempts > 0 {
+		v = fmt.Sprintf("%s; max=%d", v, maxAttempts)
+	}
+	req.Header.Set(f.header(), v)
+
+	return finalize(ctx, req)
+}
@@ -0,0 +1,132 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/go-camp/httpc"
+)
+
+func TestAttemptFinalizers(t *testing.T) {
+	var ids, counts []string
+	finalize := httpc.ComposeFinalizer(
+		AttemptRequestIDFinalizer{
+			IDGenerator: func() (string, error) {
+				return fmt.Sprintf("attempt-%d", len(ids)+1), nil
+			},
+		}.Finalizer,
+		AttemptCountFinalizer{}.Finalizer,
+	)(
+		func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+			ids = append(ids, req.Header.Get("X-Request-Id"))
+			counts = append(counts, req.Header.Get("X-Request-Attempt"))
+			if len(ids) < 3 {
+				err = &httpc.ResponseError{
+					Response: &http.Response{StatusCode: http.StatusServiceUnavailable},
+					Err:      errors.New("unavailable"),
+				}
+			}
+			return
+		},
+	)
+	build := httpc.ComposeBuilder(
+		RequestIDBuilder{
+			Header:      "X-Operation-Id",
+			IDGenerator: func() (string, error) { return "operation", nil },
+		}.Builder,
+		RetryBuilder{
+			Retryer: BasicRetryer{Options: BasicRetryerOptions{Delayer: NopRetryDelayer}},
+		}.Builder,
+	)(
+		func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+			if req.Header.Get("X-Operation-Id") != "operation" {
+				t.Fatalf("expect operation id is operation, got %q", req.Header.Get("X-Operation-Id"))
+			}
+			return finalize(ctx, req)
+		},
+	)
+
+	req := &httpc.Request{
+		Request: &http.Request{URL: &url.URL{}, Header: http.Header{}},
+	}
+	_, _, err := build(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expect no err, got %v", err)
+	}
+
+	expectIDs := []string{"attempt-1", "attempt-2", "attempt-3"}
+	expectCounts := []string{"attempt=1; max=3", "attempt=2; max=3", "attempt=3; max=3"}
+	for i := range expectIDs {
+		if ids[i] != expectIDs[i] {
+			t.Fatalf("expect request id of attempt %d is %s, got %s", i+1, expectIDs[i], ids[i])
+		}
+		if counts[i] != expectCounts[i] {
+			t.Fatalf("expect attempt header of attempt %d is %s, got %s", i+1, expectCounts[i], counts[i])
+		}
+	}
+	if req.Header.Get("X-Request-Id") != "" {
+		t.Fatalf("expect attempt request id is not set to the call request")
+	}
+}
+
+func TestAttemptCountFinalizerRetryPolicy(t *testing.T) {
+	var counts []string
+	build := RetryBuilder{
+		Retryer: RetryPolicy{
+			Retryer: BasicRetryer{Options: BasicRetryerOptions{Delayer: NopRetryDelayer}},
+			Rules: []RetryRule{
+				{StatusCodes: []int{http.StatusServiceUnavailable}, MaxAttempts: 5},
+			},
+		},
+	}.Builder(httpc.BuildFunc(AttemptCountFinalizer{}.Finalizer(
+		func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+			counts = append(counts, req.Header.Get("X-Request-Attempt"))
+			err = &httpc.ResponseError{
+				Response: &http.Response{StatusCode: http.StatusServiceUnavailable},
+				Err:      errors.New("unavailable"),
+			}
+			return
+		},
+	)))
+
+	req := &httpc.Request{
+		Request: &http.Request{Method: http.MethodGet, URL: &url.URL{}, Header: http.Header{}},
+	}
+	build(context.Background(), req)
+
+	expectCounts := []string{
+		"attempt=1; max=3", "attempt=2; max=5", "attempt=3; max=5", "attempt=4; max=5", "attempt=5; max=5",
+	}
+	if len(counts) != len(expectCounts) {
+		t.Fatalf("expect %d attempts, got %d", len(expectCounts), len(counts))
+	}
+	for i := range expectCounts {
+		if counts[i] != expectCounts[i] {
+			t.Fatalf("expect attempt header of attempt %d is %s, got %s", i+1, expectCounts[i], counts[i])
+		}
+	}
+}
+
+func TestAttemptCountFinalizerWithoutRetry(t *testing.T) {
+	var count string
+	finalize := AttemptCountFinalizer{Header: "Amz-Sdk-Request"}.Finalizer(
+		func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+			count = req.Header.Get("Amz-Sdk-Request")
+			return
+		},
+	)
+
+	req := &httpc.Request{
+		Request: &http.Request{URL: &url.URL{}, Header: http.Header{}},
+	}
+	if _, _, err := finalize(context.Background(), req); err != nil {
+		t.Fatalf("expect no err, got %v", err)
+	}
+	if count != "attempt=1" {
+		t.Fatalf("expect attempt header is attempt=1, got %s", count)
+	}
+}
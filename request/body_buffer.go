@@ -0,0 +1,202 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/go-camp/httpc"
+)
+
+const DefaultBodyBufferMemoryLimit = 1 << 20
+
+// bufferedBody makes a non-seekable reader seekable.
+// The content read from src is kept in memory,
+// and spills to a temp file when the content exceeds limit.
+type bufferedBody struct {
+	src     io.Reader
+	limit   int64
+	tempDir string
+
+	mem  []byte
+	file *os.File
+	size int64
+	pos  int64
+	eof  bool
+}
+
+func (b *bufferedBody) write(p []byte) error {
+	if b.file == nil && b.size+int64(len(p)) > b.limit {
+		f, err := os.CreateTemp(b.tempDir, "httpc-body-*")
+		if err != nil {
+			return err
+		}
+		b.file = f
+		if _, err = f.Write(b.mem); err != nil {
+			return err
+		}
+		b.mem = nil
+	}
+	if b.file != nil {
+		if _, err := b.file.WriteAt(p, b.size); err != nil {
+			return err
+		}
+	} else {
+		b.mem = append(b.mem, p...)
+	}
+	b.size += int64(len(p))
+	return nil
+}
+
+// fill reads src until size reaches n or src returns EOF.
+// If n is negative, fill reads src until EOF.
+func (b *bufferedBody) fill(n int64) error {
+	var buf [32 << 10]byte
+	for !b.eof && (n < 0 || b.size < n) {
+		m, err := b.src.Read(buf[:])
+		if m > 0 {
+			if werr := b.write(buf[:m]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			b.eof = true
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *bufferedBody) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if b.pos >= b.size {
+		if err := b.fill(b.pos + int64(len(p))); err != nil {
+			return 0, err
+		}
+		if b.pos >= b.size {
+			return 0, io.EOF
+		}
+	}
+
+	if remain := b.size - b.pos; int64(len(p)) > remain {
+		p = p[:remain]
+	}
+	var n int
+	if b.file != nil {
+		var err error
+		n, err = b.file.ReadAt(p, b.pos)
+		if err != nil && err != io.EOF {
+			return n, err
+		}
+	} else {
+		n = copy(p, b.mem[b.pos:])
+	}
+	b.pos += int64(n)
+	return n, nil
+}
+
+func (b *bufferedBody) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = b.pos + offset
+	case io.SeekEnd:
+		if err := b.fill(-1); err != nil {
+			return 0, err
+		}
+		pos = b.size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if pos < 0 {
+		return 0, errors.New("negative position")
+	}
+	b.pos = pos
+	return pos, nil
+}
+
+func (b *bufferedBody) Close() error {
+	if b.file == nil {
+		return nil
+	}
+	name := b.file.Name()
+	err := b.file.Close()
+	if rerr := os.Remove(name); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// BodyBufferBuilder makes a non-seekable request body seekable,
+// so that the following builders which require io.Seeker,
+// such as ContentMD5Builder and RetryBuilder, can work with streaming bodies.
+//
+// The body content is buffered in memory up to MemoryLimit bytes,
+// the content beyond the limit spills to a temp file which is removed when the call is done.
+// The body is read lazily, as the following builders read and seek it.
+type BodyBufferBuilder struct {
+	// If MemoryLimit is 0, then DefaultBodyBufferMemoryLimit is used.
+	MemoryLimit int64
+	// TempDir is the directory of the temp file.
+	// If TempDir is empty, the default directory for temporary files is used.
+	TempDir string
+}
+
+type bodyBufferError struct {
+	While string
+	Err   error
+}
+
+func (e *bodyBufferError) Error() string {
+	return fmt.Sprintf("request body buffer builder, %s failed, %v", e.While, e.Err)
+}
+
+func (e *bodyBufferError) Unwrap() error {
+	return e.Err
+}
+
+func (b BodyBufferBuilder) ID() string { return "BodyBufferBuilder" }
+
+func (b BodyBufferBuilder) Builder(build httpc.BuildFunc) httpc.BuildFunc {
+	return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
+		return b.build(ctx, req, build)
+	}
+}
+
+func (b BodyBufferBuilder) memoryLimit() int64 {
+	if b.MemoryLimit == 0 {
+		return DefaultBodyBufferMemoryLimit
+	}
+	return b.MemoryLimit
+}
+
+func (b BodyBufferBuilder) build(ctx context.Context, req *httpc.Request, build httpc.BuildFunc) (
+	output interface{}, md httpc.Metadata, err error,
+) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return build(ctx, req)
+	}
+	if _, ok := req.Body.(io.Seeker); ok {
+		return build(ctx, req)
+	}
+
+	body := &bufferedBody{
+		src:     req.Body,
+		limit:   b.memoryLimit(),
+		tempDir: b.TempDir,
+	}
+	req.Body = body
+	output, md, err = build(ctx, req)
+	if cerr := body.Close(); cerr != nil && err == nil {
+		err = &bodyBufferError{While: "remove temp file", Err: cerr}
+	}
+	return
+}
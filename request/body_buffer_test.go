@@ -0,0 +1,134 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-camp/httpc"
+)
+
+func TestBodyBufferBuilder(t *testing.T) {
+	testCases := []struct {
+		Name        string
+		MemoryLimit int64
+		Content     string
+
+		ExpectSpill bool
+	}{
+		{
+			Name:        "memory",
+			MemoryLimit: 64,
+			Content:     "message digest",
+		},
+		{
+			Name:        "spill to temp file",
+			MemoryLimit: 4,
+			Content:     "message digest",
+			ExpectSpill: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			pr, pw := io.Pipe()
+			go func() {
+				pw.Write([]byte(tc.Content))
+				pw.Close()
+			}()
+
+			var spilled bool
+			var tempFile string
+			var buildCount int
+			build := httpc.ComposeBuilder(
+				BodyBufferBuilder{MemoryLimit: tc.MemoryLimit, TempDir: t.TempDir()}.Builder,
+				ContentLengthBuilder{}.Builder,
+				ContentMD5Builder{}.Builder,
+				RetryBuilder{
+					Retryer: BasicRetryer{Options: BasicRetryerOptions{Delayer: NopRetryDelayer}},
+				}.Builder,
+			)(func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+				buildCount++
+				body, err := io.ReadAll(req.Body)
+				if err != nil {
+					return output, md, err
+				}
+				if string(body) != tc.Content {
+					t.Errorf("expect body is %q, got %q", tc.Content, body)
+				}
+				if bb, ok := req.Body.(*bufferedBody); ok && bb.file != nil {
+					spilled = true
+					tempFile = bb.file.Name()
+				}
+				if buildCount == 1 {
+					err = &testTimeoutError{}
+				}
+				return
+			})
+
+			req := &httpc.Request{
+				Request: &http.Request{
+					Method: http.MethodPut,
+					URL:    &url.URL{},
+					Header: http.Header{},
+				},
+				Body: pr,
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			_, _, err := build(ctx, req)
+			if err != nil {
+				t.Fatalf("expect no err, got %v", err)
+			}
+			if buildCount != 2 {
+				t.Fatalf("expect 2 attempts, got %d", buildCount)
+			}
+			if req.ContentLength != int64(len(tc.Content)) {
+				t.Fatalf("expect content length is %d, got %d", len(tc.Content), req.ContentLength)
+			}
+			if m := req.Header.Get("Content-MD5"); m != "+WtpfXy3k41SWi8xqvFh0A==" {
+				t.Fatalf("expect content md5, got %s", m)
+			}
+			if spilled != tc.ExpectSpill {
+				t.Fatalf("expect spilled is %v, got %v", tc.ExpectSpill, spilled)
+			}
+			if tempFile != "" {
+				if _, err := os.Stat(tempFile); !os.IsNotExist(err) {
+					t.Fatalf("expect temp file is removed, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestBufferedBodySeek(t *testing.T) {
+	body := &bufferedBody{src: strings.NewReader("0123456789"), limit: 4}
+	defer body.Close()
+
+	buf := make([]byte, 3)
+	if _, err := io.ReadFull(body, buf); err != nil || string(buf) != "012" {
+		t.Fatalf("expect read 012, got %q, %v", buf, err)
+	}
+	if pos, err := body.Seek(6, io.SeekStart); err != nil || pos != 6 {
+		t.Fatalf("expect pos is 6, got %d, %v", pos, err)
+	}
+	if _, err := io.ReadFull(body, buf); err != nil || string(buf) != "678" {
+		t.Fatalf("expect read 678, got %q, %v", buf, err)
+	}
+	if pos, err := body.Seek(-2, io.SeekEnd); err != nil || pos != 8 {
+		t.Fatalf("expect pos is 8, got %d, %v", pos, err)
+	}
+	rest, err := io.ReadAll(body)
+	if err != nil || !bytes.Equal(rest, []byte("89")) {
+		t.Fatalf("expect read 89, got %q, %v", rest, err)
+	}
+	if _, err := body.Seek(-1, io.SeekStart); err == nil {
+		t.Fatalf("expect negative position error")
+	}
+}
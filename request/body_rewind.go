@@ -26,3 +26,27 @@ func (r *rewindReader) Rewind() error {
 	_, err := r.ReadSeeker.Seek(r.startPos, io.SeekStart)
 	return err
 }
+
+// ReadRewind calls fn with body, then rewinds body to the position before the call.
+// body must implement io.Seeker.
+func ReadRewind(body io.Reader, fn func(r io.Reader) error) error {
+	rr, err := newRewindReader(body)
+	if err != nil {
+		return err
+	}
+	if err = fn(rr); err != nil {
+		rr.Rewind()
+		return err
+	}
+	return rr.Rewind()
+}
+
+// RewindFunc returns a function which rewinds body to its current position.
+// body must implement io.Seeker.
+func RewindFunc(body io.Reader) (rewind func() error, err error) {
+	rr, err := newRewindReader(body)
+	if err != nil {
+		return nil, err
+	}
+	return rr.Rewind, nil
+}
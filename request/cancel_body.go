@@ -0,0 +1,66 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-camp/httpc"
+	"github.com/go-camp/httpc/response"
+)
+
+// resultResponse returns the response of a build result,
+// which is set by ResponseDeserializer, returned as the output or carried by a ResponseError.
+func resultResponse(output interface{}, md httpc.Metadata, err error) *http.Response {
+	if resp := response.GetResponse(md); resp != nil {
+		return resp
+	}
+	if resp, ok := output.(*http.Response); ok && resp != nil {
+		return resp
+	}
+	var respErr *httpc.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.Response
+	}
+	return nil
+}
+
+// CloseResult closes the response body of a build result which is discarded,
+// such as a failed attempt which is sent again.
+// The response is set by ResponseDeserializer, returned as the output or carried by a ResponseError,
+// the output is also closed if it implements io.Closer.
+func CloseResult(output interface{}, md httpc.Metadata, err error) {
+	if resp := resultResponse(output, md, err); resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if c, ok := output.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// cancelOnClose defers cancel until the response body of a returned build result is closed,
+// so that the body can still be read after the call returns.
+// cancel is called at once if the result has no body to read,
+// it is left to the parent context if the body of a successful result is unknown.
+func cancelOnClose(output interface{}, md httpc.Metadata, err error, cancel context.CancelFunc) {
+	resp := resultResponse(output, md, err)
+	switch {
+	case resp == nil && err == nil:
+	case resp == nil || resp.Body == nil || resp.Body == http.NoBody:
+		cancel()
+	default:
+		resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	}
+}
@@ -0,0 +1,310 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-camp/httpc"
+)
+
+//go:generate stringer -type=CircuitState -trimprefix=Circuit
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+const (
+	DefaultCircuitWindow              = 10 * time.Second
+	DefaultCircuitMinRequests         = 10
+	DefaultCircuitFailureRatio        = 0.5
+	DefaultCircuitOpenTimeout         = 30 * time.Second
+	DefaultCircuitHalfOpenMaxRequests = 1
+)
+
+// CircuitOpenError is returned by CircuitBreakerBuilder when the circuit is open,
+// or the half-open circuit has reached the max number of probe requests.
+type CircuitOpenError struct {
+	Key   string
+	State CircuitState
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("request circuit breaker, circuit %s is %s", e.Key, e.State)
+}
+
+// RetryableError always returns false, the call fails fast while the circuit is open.
+func (e *CircuitOpenError) RetryableError() bool {
+	return false
+}
+
+type circuit struct {
+	state      CircuitState
+	generation uint64
+	expiry     time.Time
+	requests   int
+	failures   int
+	probes     int
+}
+
+// CircuitBreaker tracks the failure ratio of every key.
+//
+// A closed circuit opens when at least MinRequests requests are done in the Window
+// and the failure ratio reaches FailureRatio.
+// An open circuit becomes half-open after OpenTimeout,
+// then at most HalfOpenMaxRequests probe requests are allowed,
+// the circuit closes if a probe request succeeds and opens again if a probe request fails.
+type CircuitBreaker struct {
+	// If Window is 0, then DefaultCircuitWindow is used.
+	Window time.Duration
+	// If MinRequests is 0, then DefaultCircuitMinRequests is used.
+	MinRequests int
+	// If FailureRatio is 0, then DefaultCircuitFailureRatio is used.
+	FailureRatio float64
+	// If OpenTimeout is 0, then DefaultCircuitOpenTimeout is used.
+	OpenTimeout time.Duration
+	// If HalfOpenMaxRequests is 0, then DefaultCircuitHalfOpenMaxRequests is used.
+	HalfOpenMaxRequests int
+	// If Now is nil, then time.Now is used.
+	Now func() time.Time
+
+	mux      sync.Mutex
+	circuits map[string]*circuit
+}
+
+func (b *CircuitBreaker) window() time.Duration {
+	if b.Window == 0 {
+		return DefaultCircuitWindow
+	}
+	return b.Window
+}
+
+func (b *CircuitBreaker) minRequests() int {
+	if b.MinRequests == 0 {
+		return DefaultCircuitMinRequests
+	}
+	return b.MinRequests
+}
+
+func (b *CircuitBreaker) failureRatio() float64 {
+	if b.FailureRatio == 0 {
+		return DefaultCircuitFailureRatio
+	}
+	return b.FailureRatio
+}
+
+func (b *CircuitBreaker) openTimeout() time.Duration {
+	if b.OpenTimeout == 0 {
+		return DefaultCircuitOpenTimeout
+	}
+	return b.OpenTimeout
+}
+
+func (b *CircuitBreaker) halfOpenMaxRequests() int {
+	if b.HalfOpenMaxRequests == 0 {
+		return DefaultCircuitHalfOpenMaxRequests
+	}
+	return b.HalfOpenMaxRequests
+}
+
+func (b *CircuitBreaker) now() time.Time {
+	if b.Now == nil {
+		return time.Now()
+	}
+	return b.Now()
+}
+
+// circuit returns the circuit of key with the state at now.
+func (b *CircuitBreaker) circuit(key string, now time.Time) *circuit {
+	if b.circuits == nil {
+		b.circuits = make(map[string]*circuit)
+	}
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{}
+		b.circuits[key] = c
+		b.setState(c, CircuitClosed, now)
+	}
+
+	switch c.state {
+	case CircuitClosed:
+		if !now.Before(c.expiry) {
+			b.setState(c, CircuitClosed, now)
+		}
+	case CircuitOpen:
+		if !now.Before(c.expiry) {
+			b.setState(c, CircuitHalfOpen, now)
+		}
+	}
+	return c
+}
+
+func (b *CircuitBreaker) setState(c *circuit, state CircuitState, now time.Time) {
+	c.state = state
+	c.generation++
+	c.requests = 0
+	c.failures = 0
+	c.probes = 0
+	switch state {
+	case CircuitClosed:
+		c.expiry = now.Add(b.window())
+	case CircuitOpen:
+		c.expiry = now.Add(b.openTimeout())
+	default:
+		c.expiry = time.Time{}
+	}
+}
+
+// State returns the current state of the circuit of key.
+func (b *CircuitBreaker) State(key string) CircuitState {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.circuit(key, b.now()).state
+}
+
+// allow checks if a request of key can be done.
+func (b *CircuitBreaker) allow(key string) (generation uint64, err error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	c := b.circuit(key, b.now())
+	switch c.state {
+	case CircuitOpen:
+		return 0, &CircuitOpenError{Key: key, State: c.state}
+	case CircuitHalfOpen:
+		if c.probes >= b.halfOpenMaxRequests() {
+			return 0, &CircuitOpenError{Key: key, State: c.state}
+		}
+		c.probes++
+	}
+	return c.generation, nil
+}
+
+// release frees the probe slot of a request allowed at generation without recording its result.
+func (b *CircuitBreaker) release(key string, generation uint64) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	c := b.circuit(key, b.now())
+	if c.generation == generation && c.state == CircuitHalfOpen && c.probes > 0 {
+		c.probes--
+	}
+}
+
+// record records the result of a request allowed at generation.
+func (b *CircuitBreaker) record(key string, generation uint64, failure bool) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	now := b.now()
+	c := b.circuit(key, now)
+	if c.generation != generation {
+		return
+	}
+
+	switch c.state {
+	case CircuitClosed:
+		c.requests++
+		if failure {
+			c.failures++
+		}
+		if c.requests >= b.minRequests() &&
+			float64(c.failures)/float64(c.requests) >= b.failureRatio() {
+			b.setState(c, CircuitOpen, now)
+		}
+	case CircuitHalfOpen:
+		if failure {
+			b.setState(c, CircuitOpen, now)
+		} else {
+			b.setState(c, CircuitClosed, now)
+		}
+	}
+}
+
+// CircuitBreakerBuilder fails fast with CircuitOpenError while the circuit of the request is open.
+//
+// If CircuitBreakerBuilder is added before RetryBuilder, it tracks the result of every call,
+// otherwise it tracks the result of every attempt.
+// The result of a request canceled by the caller is not tracked, and a request which panics is a failure.
+type CircuitBreakerBuilder struct {
+	// Breaker is usually shared by the calls of a client.
+	Breaker *CircuitBreaker
+	// Key returns the circuit key of the request.
+	// If Key is nil, the service and operation names from ServiceOperationNameInitializer are used.
+	Key func(ctx context.Context, req *httpc.Request) string
+	// ErrorChecker checks if an error is a failure,
+	// an error is a failure if it is retryable or its ErrorFault is ErrorFaultServer.
+	// An error with ErrorFaultClient is never a failure.
+	// If ErrorChecker is nil, then DefaultRetryableChecker is used.
+	ErrorChecker RetryableChecker
+}
+
+func (b CircuitBreakerBuilder) ID() string { return "CircuitBreakerBuilder" }
+
+func (b CircuitBreakerBuilder) Builder(build httpc.BuildFunc) httpc.BuildFunc {
+	return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
+		return b.build(ctx, req, build)
+	}
+}
+
+func (b CircuitBreakerBuilder) key(ctx context.Context, req *httpc.Request) string {
+	if b.Key != nil {
+		return b.Key(ctx, req)
+	}
+	return GetServiceNameFromContext(ctx) + "/" + GetOperationNameFromContext(ctx)
+}
+
+func (b CircuitBreakerBuilder) errorChecker() RetryableChecker {
+	if b.ErrorChecker == nil {
+		return DefaultRetryableChecker
+	}
+	return b.ErrorChecker
+}
+
+func (b CircuitBreakerBuilder) isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var apiErr httpc.APIError
+	hasAPIErr := errors.As(err, &apiErr)
+	if hasAPIErr && apiErr.ErrorFault() == httpc.ErrorFaultClient {
+		return false
+	}
+	if b.errorChecker().Check(err) == RetryableYes {
+		return true
+	}
+	return hasAPIErr && apiErr.ErrorFault() == httpc.ErrorFaultServer
+}
+
+func (b CircuitBreakerBuilder) build(ctx context.Context, req *httpc.Request, build httpc.BuildFunc) (
+	output interface{}, md httpc.Metadata, err error,
+) {
+	if b.Breaker == nil {
+		return build(ctx, req)
+	}
+
+	key := b.key(ctx, req)
+	generation, err := b.Breaker.allow(key)
+	if err != nil {
+		return
+	}
+	done := false
+	defer func() {
+		switch {
+		case !done:
+			b.Breaker.record(key, generation, true)
+		case ctx.Err() != nil || errors.Is(err, context.Canceled):
+			b.Breaker.release(key, generation)
+		default:
+			b.Breaker.record(key, generation, b.isFailure(err))
+		}
+	}()
+	output, md, err = build(ctx, req)
+	done = true
+	return
+}
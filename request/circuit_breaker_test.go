@@ -0,0 +1,119 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-camp/httpc"
+)
+
+func TestCircuitBreakerBuilder(t *testing.T) {
+	now := time.Unix(1000, 0)
+	breaker := &CircuitBreaker{
+		MinRequests: 2,
+		OpenTimeout: time.Second,
+		Now:         func() time.Time { return now },
+	}
+
+	var buildErr error
+	var buildPanic bool
+	build := CircuitBreakerBuilder{Breaker: breaker}.Builder(
+		func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+			if buildPanic {
+				panic("build")
+			}
+			return output, md, buildErr
+		},
+	)
+	baseCtx := context.WithValue(context.Background(), serviceNameKey{}, "service")
+	baseCtx = context.WithValue(baseCtx, operationNameKey{}, "operation")
+	canceledCtx, cancel := context.WithCancel(baseCtx)
+	cancel()
+	key := "service/operation"
+	req := &httpc.Request{
+		Request: &http.Request{URL: &url.URL{}, Header: http.Header{}},
+	}
+	serverErr := &httpc.ResponseError{
+		Response: &http.Response{StatusCode: http.StatusServiceUnavailable},
+		Err:      errors.New("unavailable"),
+	}
+	clientErr := &httpc.ResponseError{
+		Response: &http.Response{StatusCode: http.StatusServiceUnavailable},
+		Err:      &httpc.GenericAPIError{Code: "Invalid", Fault: httpc.ErrorFaultClient},
+	}
+
+	steps := []struct {
+		Name       string
+		Advance    time.Duration
+		BuildErr   error
+		Canceled   bool
+		BuildPanic bool
+
+		ExpectOpenError bool
+		ExpectState     CircuitState
+	}{
+		{Name: "client fault", BuildErr: clientErr, ExpectState: CircuitClosed},
+		{Name: "client fault again", BuildErr: clientErr, ExpectState: CircuitClosed},
+		{Name: "window expired", Advance: DefaultCircuitWindow, BuildErr: serverErr, ExpectState: CircuitClosed},
+		{Name: "server fault", BuildErr: serverErr, ExpectState: CircuitOpen},
+		{Name: "fail fast", ExpectOpenError: true, ExpectState: CircuitOpen},
+		{Name: "half open probe fails", Advance: time.Second, BuildErr: serverErr, ExpectState: CircuitOpen},
+		{Name: "half open probe panics", Advance: time.Second, BuildPanic: true, ExpectState: CircuitOpen},
+		{Name: "half open probe canceled", Advance: time.Second, BuildErr: context.Canceled, Canceled: true, ExpectState: CircuitHalfOpen},
+		{Name: "half open probe canceled by error", BuildErr: &url.Error{Op: "Get", Err: context.Canceled}, ExpectState: CircuitHalfOpen},
+		{Name: "half open probe succeeds", ExpectState: CircuitClosed},
+	}
+	for _, step := range steps {
+		now = now.Add(step.Advance)
+		buildErr = step.BuildErr
+		buildPanic = step.BuildPanic
+		ctx := baseCtx
+		if step.Canceled {
+			ctx = canceledCtx
+		}
+		err := func() (err error) {
+			defer func() {
+				if v := recover(); v != nil && !step.BuildPanic {
+					panic(v)
+				}
+			}()
+			_, _, err = build(ctx, req)
+			return
+		}()
+
+		var openErr *CircuitOpenError
+		if isOpenErr := errors.As(err, &openErr); isOpenErr != step.ExpectOpenError {
+			t.Fatalf("%s: expect circuit open error %v, got %v", step.Name, step.ExpectOpenError, err)
+		}
+		if state := breaker.State(key); state != step.ExpectState {
+			t.Fatalf("%s: expect state is %s, got %s", step.Name, step.ExpectState, state)
+		}
+	}
+}
+
+func TestCircuitBreakerHalfOpenMaxRequests(t *testing.T) {
+	now := time.Unix(1000, 0)
+	breaker := &CircuitBreaker{
+		MinRequests: 1,
+		Now:         func() time.Time { return now },
+	}
+	gen, _ := breaker.allow("key")
+	breaker.record("key", gen, true)
+	now = now.Add(DefaultCircuitOpenTimeout)
+
+	if _, err := breaker.allow("key"); err != nil {
+		t.Fatalf("expect probe is allowed, got %v", err)
+	}
+	_, err := breaker.allow("key")
+	var openErr *CircuitOpenError
+	if !errors.As(err, &openErr) || openErr.State != CircuitHalfOpen {
+		t.Fatalf("expect half-open circuit open error, got %v", err)
+	}
+	if DefaultRetryableChecker.Check(err) != RetryableNo {
+		t.Fatalf("expect circuit open error is not retryable")
+	}
+}
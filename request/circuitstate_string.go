@@ -0,0 +1,26 @@
+// Code generated by "stringer -type=CircuitState -trimprefix=Circuit"; DO NOT EDIT.
+
+package request
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[CircuitClosed-0]
+	_ = x[CircuitOpen-1]
+	_ = x[CircuitHalfOpen-2]
+}
+
+const _CircuitState_name = "ClosedOpenHalfOpen"
+
+var _CircuitState_index = [...]uint8{0, 6, 10, 18}
+
+func (i CircuitState) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_CircuitState_index)-1 {
+		return "CircuitState(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _CircuitState_name[_CircuitState_index[idx]:_CircuitState_index[idx+1]]
+}
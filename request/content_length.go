@@ -27,6 +27,8 @@ func (e *contentLengthError) Unwrap() error {
 	return e.Err
 }
 
+func (b ContentLengthBuilder) ID() string { return "ContentLengthBuilder" }
+
 func (b ContentLengthBuilder) Builder(build httpc.BuildFunc) httpc.BuildFunc {
 	return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
 		return b.build(ctx, req, build)
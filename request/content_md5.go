@@ -14,7 +14,8 @@ import (
 // ContentMD5Builder calculates the md5 checksum of the request body
 // and sets the checksum to the value of the Content-MD5 header.
 //
-// This builder requires the request Body to implement io.Seeker interface.
+// This builder requires the request Body to implement io.Seeker interface,
+// a non-seekable Body can be made seekable by BodyBufferBuilder.
 type ContentMD5Builder struct {
 }
 
@@ -40,6 +41,8 @@ func calculateMD5(r io.Reader) (string, error) {
 	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
 }
 
+func (b ContentMD5Builder) ID() string { return "ContentMD5Builder" }
+
 func (b ContentMD5Builder) Builder(build httpc.BuildFunc) httpc.BuildFunc {
 	return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
 		return b.build(ctx, req, build)
@@ -0,0 +1,98 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/go-camp/httpc"
+	"github.com/go-camp/httpc/response"
+)
+
+// DumpBuilder enables response.DumpDeserializer to dump every attempt of the call,
+// if Enabled is true or the environment variable response.DumpEnv is true.
+//
+// DumpBuilder should be added before RetryBuilder,
+// the request body is read once and rewinded, so it must implement io.Seeker to be dumped.
+type DumpBuilder struct {
+	// If Writer is nil, then os.Stderr is used.
+	Writer io.Writer
+	// Enabled enables the dump regardless of response.DumpEnv.
+	Enabled bool
+	// OmitBody omits the request and response bodies.
+	OmitBody bool
+}
+
+type dumpError struct {
+	While string
+	Err   error
+}
+
+func (e *dumpError) Error() string {
+	return fmt.Sprintf("request dump builder, %s failed, %v", e.While, e.Err)
+}
+
+func (e *dumpError) Unwrap() error {
+	return e.Err
+}
+
+func (b DumpBuilder) ID() string { return "DumpBuilder" }
+
+func (b DumpBuilder) Builder(build httpc.BuildFunc) httpc.BuildFunc {
+	return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
+		return b.build(ctx, req, build)
+	}
+}
+
+func (b DumpBuilder) writer() io.Writer {
+	if b.Writer == nil {
+		return os.Stderr
+	}
+	return b.Writer
+}
+
+func (b DumpBuilder) build(ctx context.Context, req *httpc.Request, build httpc.BuildFunc) (
+	output interface{}, md httpc.Metadata, err error,
+) {
+	if !b.Enabled && !response.DumpEnabledByEnv() {
+		return build(ctx, req)
+	}
+
+	dump := &response.Dump{Writer: b.writer(), Body: !b.OmitBody}
+	if dump.Body && req.Body != nil && req.Body != http.NoBody {
+		if _, ok := req.Body.(io.Seeker); ok {
+			err = ReadRewind(req.Body, func(r io.Reader) (err error) {
+				dump.RequestBody, err = io.ReadAll(r)
+				return err
+			})
+			if err != nil {
+				err = &dumpError{While: "read body", Err: err}
+				return
+			}
+		} else {
+			dump.RequestBody = []byte("(non-seekable body omitted)")
+		}
+	}
+
+	req.Request = req.Request.WithContext(response.ContextWithDump(req.Context(), dump))
+	return build(ctx, req)
+}
+
+// WithDump dumps every attempt of the current call to w.
+// A DumpBuilder step is added to the front of the Build steps,
+// and a DumpDeserializer step is added to the end of the Deserialize steps if absent,
+// so the Handler must not have a DumpDeserializer outside of its Stack.
+func WithDump(w io.Writer) func(*httpc.Options) {
+	return func(o *httpc.Options) {
+		b := DumpBuilder{Writer: w, Enabled: true}
+		if _, err := o.Stack.Build.Swap(b.ID(), b.Builder); err != nil {
+			o.Stack.Build.Add(b.ID(), b.Builder, httpc.Before)
+		}
+		d := response.DumpDeserializer{}
+		if _, ok := o.Stack.Deserialize.Get(d.ID()); !ok {
+			o.Stack.Deserialize.Add(d.ID(), d.Deserializer, httpc.After)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-camp/httpc"
+	"github.com/go-camp/httpc/response"
+)
+
+func newDumpTestHandler(bodies *[]string) httpc.Handler {
+	return httpc.Handler{
+		Serializer: func(serialize httpc.SerializeFunc) httpc.SerializeFunc {
+			return func(ctx context.Context, input httpc.SerializeInput) (output interface{}, md httpc.Metadata, err error) {
+				input.Request = &httpc.Request{
+					Request: &http.Request{
+						Method: http.MethodPut,
+						URL:    &url.URL{Scheme: "http", Host: "example.com", Path: "/items/1"},
+						Header: http.Header{},
+					},
+					Body: strings.NewReader("request body"),
+				}
+				return serialize(ctx, input)
+			}
+		},
+		Builder: RetryBuilder{
+			Retryer: BasicRetryer{Options: BasicRetryerOptions{Delayer: NopRetryDelayer}},
+		}.Builder,
+		Deserializer: func(deserialize httpc.DeserializeFunc) httpc.DeserializeFunc {
+			return func(req *http.Request) (output httpc.DeserializeOutput, md httpc.Metadata, err error) {
+				output, md, err = deserialize(req)
+				if err != nil {
+					return
+				}
+				body, _ := io.ReadAll(output.Response.Body)
+				*bodies = append(*bodies, string(body))
+				if output.Response.StatusCode != http.StatusOK {
+					err = &httpc.ResponseError{Response: output.Response, Err: io.ErrUnexpectedEOF}
+				}
+				return
+			}
+		},
+		Do: func(req *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(req.Body)
+			if string(body) != "request body" {
+				return nil, io.ErrUnexpectedEOF
+			}
+			statusCode := http.StatusOK
+			if len(*bodies) == 0 {
+				statusCode = http.StatusServiceUnavailable
+			}
+			return &http.Response{
+				StatusCode:    statusCode,
+				ProtoMajor:    1,
+				ProtoMinor:    1,
+				Header:        http.Header{},
+				ContentLength: int64(len("response body")),
+				Body:          io.NopCloser(strings.NewReader("response body")),
+			}, nil
+		},
+	}
+}
+
+func TestWithDump(t *testing.T) {
+	var bodies []string
+	var buf bytes.Buffer
+	h := newDumpTestHandler(&bodies)
+	_, _, err := h.Handle(context.Background(), nil, WithDump(&buf))
+	if err != nil {
+		t.Fatalf("expect no err, got %v", err)
+	}
+
+	dump := buf.String()
+	if n := strings.Count(dump, "PUT /items/1 HTTP/1.1\r\nHost: example.com\r\n"); n != 2 {
+		t.Fatalf("expect 2 request dumps, got %d:\n%s", n, dump)
+	}
+	if n := strings.Count(dump, "request body"); n != 2 {
+		t.Fatalf("expect 2 request bodies, got %d:\n%s", n, dump)
+	}
+	if !strings.Contains(dump, "HTTP/1.1 503 Service Unavailable\r\n") ||
+		!strings.Contains(dump, "HTTP/1.1 200 OK\r\n") {
+		t.Fatalf("expect response dumps, got:\n%s", dump)
+	}
+	if n := strings.Count(dump, "response body"); n != 2 {
+		t.Fatalf("expect 2 response bodies, got %d:\n%s", n, dump)
+	}
+	for _, body := range bodies {
+		if body != "response body" {
+			t.Fatalf("expect response body is not consumed, got %s", body)
+		}
+	}
+}
+
+func TestDumpBuilderEnv(t *testing.T) {
+	testCases := []struct {
+		Name string
+		Env  string
+
+		ExpectDump bool
+	}{
+		{Name: "enabled", Env: "true", ExpectDump: true},
+		{Name: "disabled", Env: "", ExpectDump: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Setenv(response.DumpEnv, tc.Env)
+			var bodies []string
+			var buf bytes.Buffer
+			h := newDumpTestHandler(&bodies)
+			h.Stack = &httpc.Stack{}
+			h.Stack.Build.Add(DumpBuilder{}.ID(), DumpBuilder{Writer: &buf, OmitBody: true}.Builder, httpc.After)
+			h.Stack.Deserialize.Add(response.DumpDeserializer{}.ID(), response.DumpDeserializer{}.Deserializer, httpc.After)
+			_, _, err := h.Handle(context.Background(), nil)
+			if err != nil {
+				t.Fatalf("expect no err, got %v", err)
+			}
+
+			if dumped := buf.Len() > 0; dumped != tc.ExpectDump {
+				t.Fatalf("expect dump %v, got:\n%s", tc.ExpectDump, buf.String())
+			}
+			if strings.Contains(buf.String(), "body") {
+				t.Fatalf("expect bodies are omitted, got:\n%s", buf.String())
+			}
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package request
 
 const (
-	headerContentMD5 = "Content-MD5"
-	headerUserAgent  = "User-Agent"
-	headerXRequestID = "X-Request-Id"
+	headerContentMD5      = "Content-MD5"
+	headerIdempotencyKey  = "Idempotency-Key"
+	headerRetryAfter      = "Retry-After"
+	headerUserAgent       = "User-Agent"
+	headerXRequestID      = "X-Request-Id"
+	headerXRequestAttempt = "X-Request-Attempt"
 )
@@ -0,0 +1,47 @@
+package request
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-camp/httpc"
+)
+
+// HeaderBuilder sets the specified headers to the request, the existing values are replaced.
+type HeaderBuilder struct {
+	Header http.Header
+}
+
+func (b HeaderBuilder) ID() string { return "HeaderBuilder" }
+
+func (b HeaderBuilder) Builder(build httpc.BuildFunc) httpc.BuildFunc {
+	return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
+		return b.build(ctx, req, build)
+	}
+}
+
+func (b HeaderBuilder) build(ctx context.Context, req *httpc.Request, build httpc.BuildFunc) (
+	output interface{}, md httpc.Metadata, err error,
+) {
+	for k, vs := range b.Header {
+		req.Header.Del(k)
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	return build(ctx, req)
+}
+
+// WithHeader sets the header key to value for the current call.
+// The header is set by a HeaderBuilder step with ID "HeaderBuilder:<key>",
+// which is added to the front of the Build steps.
+func WithHeader(key, value string) func(*httpc.Options) {
+	key = http.CanonicalHeaderKey(key)
+	return func(o *httpc.Options) {
+		b := HeaderBuilder{Header: http.Header{key: []string{value}}}
+		id := b.ID() + ":" + key
+		if _, err := o.Stack.Build.Swap(id, b.Builder); err != nil {
+			o.Stack.Build.Add(id, b.Builder, httpc.Before)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package request
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/go-camp/httpc"
+)
+
+func TestWithHeader(t *testing.T) {
+	opts := httpc.Options{Stack: &httpc.Stack{}}
+	WithHeader("x-test", "1")(&opts)
+	WithHeader("X-Test", "2")(&opts)
+	WithHeader("X-Other", "3")(&opts)
+
+	ids := opts.Stack.Build.List()
+	if len(ids) != 2 {
+		t.Fatalf("expect 2 steps, got %v", ids)
+	}
+
+	builder, _ := opts.Stack.Build.Get("HeaderBuilder:X-Test")
+	build := builder(func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+		return
+	})
+	req := &httpc.Request{
+		Request: &http.Request{
+			URL:    &url.URL{},
+			Header: http.Header{"X-Test": []string{"0"}},
+		},
+	}
+	_, _, err := build(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expect no err, got %v", err)
+	}
+	if v := req.Header.Values("X-Test"); len(v) != 1 || v[0] != "2" {
+		t.Fatalf("expect header is [2], got %v", v)
+	}
+}
+
+func TestWithHeaderConcurrent(t *testing.T) {
+	opt := WithHeader("x-test", "1")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			opts := httpc.Options{Stack: &httpc.Stack{}}
+			opt(&opts)
+			if _, ok := opts.Stack.Build.Get("HeaderBuilder:X-Test"); !ok {
+				t.Errorf("expect step HeaderBuilder:X-Test, got %v", opts.Stack.Build.List())
+			}
+		}()
+	}
+	wg.Wait()
+}
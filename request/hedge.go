@@ -0,0 +1,317 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-camp/httpc"
+)
+
+const (
+	DefaultHedgeMaxAttempts       = 2
+	DefaultHedgeDelay             = 100 * time.Millisecond
+	DefaultHedgePercentile        = 0.95
+	DefaultLatencyTrackerSize     = 1000
+	DefaultLatencyTrackerMinCount = 10
+)
+
+// LatencyTracker records the recent latencies of successful requests.
+type LatencyTracker struct {
+	// If Size is not positive, then DefaultLatencyTrackerSize is used.
+	Size int
+	// MinCount is the minimum number of latencies required to calculate a percentile.
+	// If MinCount is 0, then DefaultLatencyTrackerMinCount is used.
+	MinCount int
+
+	mux       sync.Mutex
+	latencies []time.Duration
+	next      int
+}
+
+func (t *LatencyTracker) size() int {
+	if t.Size <= 0 {
+		return DefaultLatencyTrackerSize
+	}
+	return t.Size
+}
+
+func (t *LatencyTracker) minCount() int {
+	if t.MinCount == 0 {
+		return DefaultLatencyTrackerMinCount
+	}
+	return t.MinCount
+}
+
+// Record records a latency.
+func (t *LatencyTracker) Record(latency time.Duration) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if len(t.latencies) < t.size() {
+		t.latencies = append(t.latencies, latency)
+		return
+	}
+	t.latencies[t.next] = latency
+	t.next = (t.next + 1) % len(t.latencies)
+}
+
+// Percentile returns the p-th (0 < p <= 1) percentile of the recorded latencies.
+// ok is false if fewer than MinCount latencies are recorded.
+func (t *LatencyTracker) Percentile(p float64) (latency time.Duration, ok bool) {
+	t.mux.Lock()
+	if len(t.latencies) < t.minCount() {
+		t.mux.Unlock()
+		return 0, false
+	}
+	latencies := make([]time.Duration, len(t.latencies))
+	copy(latencies, t.latencies)
+	t.mux.Unlock()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	i := int(math.Ceil(p*float64(len(latencies)))) - 1
+	if i < 0 {
+		i = 0
+	} else if i >= len(latencies) {
+		i = len(latencies) - 1
+	}
+	return latencies[i], true
+}
+
+var idempotentMethods = map[string]struct{}{
+	http.MethodGet:     {},
+	http.MethodHead:    {},
+	http.MethodOptions: {},
+	http.MethodTrace:   {},
+	http.MethodPut:     {},
+	http.MethodDelete:  {},
+}
+
+func isIdempotentMethod(method string) bool {
+	if method == "" {
+		method = http.MethodGet
+	}
+	_, ok := idempotentMethods[method]
+	return ok
+}
+
+// HedgeBuilder starts another attempt if the previous attempts haven't returned within a delay,
+// the first successful result is returned and the other attempts are canceled.
+//
+// HedgeBuilder only hedges requests with idempotent methods.
+// The request body must be nil or implement io.Seeker, otherwise the request is not hedged.
+// A seekable body is read into memory, unless it also implements io.ReaderAt.
+//
+// The other attempts are canceled when HedgeBuilder returns,
+// and their responses are closed if they are set by ResponseDeserializer, returned as the output,
+// carried by a ResponseError or the output implements io.Closer.
+// The returned attempt is canceled when its response body is closed.
+type HedgeBuilder struct {
+	// MaxAttempts is the max number of concurrent attempts, including the first one.
+	// If MaxAttempts is 0, then DefaultHedgeMaxAttempts is used.
+	MaxAttempts int
+	// Delay is the delay before starting the next attempt.
+	// If Delay is 0, then DefaultHedgeDelay is used.
+	Delay time.Duration
+	// Latency records the latency of successful requests.
+	// If Latency has enough latencies, the Percentile of latencies is used as the delay.
+	Latency *LatencyTracker
+	// If Percentile is 0, then DefaultHedgePercentile is used.
+	Percentile float64
+}
+
+type hedgeError struct {
+	While string
+	Err   error
+}
+
+func (e *hedgeError) Error() string {
+	return fmt.Sprintf("request hedge builder, %s failed, %v", e.While, e.Err)
+}
+
+func (e *hedgeError) Unwrap() error {
+	return e.Err
+}
+
+func (b HedgeBuilder) ID() string { return "HedgeBuilder" }
+
+func (b HedgeBuilder) Builder(build httpc.BuildFunc) httpc.BuildFunc {
+	return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
+		return b.build(ctx, req, build)
+	}
+}
+
+func (b HedgeBuilder) maxAttempts() int {
+	if b.MaxAttempts == 0 {
+		return DefaultHedgeMaxAttempts
+	}
+	return b.MaxAttempts
+}
+
+func (b HedgeBuilder) percentile() float64 {
+	if b.Percentile == 0 {
+		return DefaultHedgePercentile
+	}
+	return b.Percentile
+}
+
+func (b HedgeBuilder) delay() time.Duration {
+	if b.Latency != nil {
+		if latency, ok := b.Latency.Percentile(b.percentile()); ok {
+			return latency
+		}
+	}
+	if b.Delay == 0 {
+		return DefaultHedgeDelay
+	}
+	return b.Delay
+}
+
+// hedgeBody returns a function which returns an independent reader of body for every attempt.
+func hedgeBody(body io.Reader) (func() io.Reader, bool, error) {
+	if body == nil || body == http.NoBody {
+		return func() io.Reader { return body }, true, nil
+	}
+	if _, ok := body.(io.Seeker); !ok {
+		return nil, false, nil
+	}
+	rr, err := newRewindReader(body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if ra, ok := body.(io.ReaderAt); ok {
+		endPos, err := rr.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, false, err
+		}
+		if err = rr.Rewind(); err != nil {
+			return nil, false, err
+		}
+		return func() io.Reader {
+			return io.NewSectionReader(ra, rr.startPos, endPos-rr.startPos)
+		}, true, nil
+	}
+
+	content, err := io.ReadAll(rr)
+	if err != nil {
+		return nil, false, err
+	}
+	if err = rr.Rewind(); err != nil {
+		return nil, false, err
+	}
+	return func() io.Reader { return bytes.NewReader(content) }, true, nil
+}
+
+type hedgeResult struct {
+	index  int
+	output interface{}
+	md     httpc.Metadata
+	err    error
+}
+
+func (b HedgeBuilder) build(ctx context.Context, req *httpc.Request, build httpc.BuildFunc) (
+	output interface{}, md httpc.Metadata, err error,
+) {
+	maxAttempts := b.maxAttempts()
+	if maxAttempts <= 1 || !isIdempotentMethod(req.Method) {
+		return build(ctx, req)
+	}
+	newBody, ok, err := hedgeBody(req.Body)
+	if err != nil {
+		err = &hedgeError{While: "prepare body", Err: err}
+		return
+	}
+	if !ok {
+		return build(ctx, req)
+	}
+
+	var cancels []context.CancelFunc
+	results := make(chan hedgeResult, maxAttempts)
+	attempt := func() {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+
+		r := req.Clone(attemptCtx)
+		r.Body = newBody()
+		go func() {
+			result := hedgeResult{index: index}
+			defer func() {
+				if v := recover(); v != nil {
+					result.err = &httpc.PanicError{Stage: httpc.StageBuild, Value: v, Stack: debug.Stack()}
+				}
+				results <- result
+			}()
+			result.output, result.md, result.err = build(attemptCtx, r)
+		}()
+	}
+
+	start := time.Now()
+	launched, pending := 1, 1
+	attempt()
+	timer := time.NewTimer(b.delay())
+	defer timer.Stop()
+
+	// the returned attempt is canceled when its response body is closed,
+	// the other attempts are canceled and the responses of the pending ones are closed after return.
+	returned := -1
+	var last hedgeResult
+	defer func() {
+		for i, cancel := range cancels {
+			if i != returned {
+				cancel()
+			}
+		}
+		if returned >= 0 {
+			cancelOnClose(output, md, err, cancels[returned])
+		}
+		if pending == 0 {
+			return
+		}
+		go func(pending int) {
+			for ; pending > 0; pending-- {
+				r := <-results
+				CloseResult(r.output, r.md, r.err)
+			}
+		}(pending)
+	}()
+
+	for {
+		select {
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				if b.Latency != nil {
+					b.Latency.Record(time.Since(start))
+				}
+				if returned >= 0 {
+					CloseResult(last.output, last.md, last.err)
+				}
+				returned = result.index
+				return result.output, result.md, nil
+			}
+			if returned >= 0 {
+				CloseResult(last.output, last.md, last.err)
+			}
+			returned, last = result.index, result
+			output, md, err = result.output, result.md, result.err
+			if pending == 0 {
+				return
+			}
+		case <-timer.C:
+			if launched < maxAttempts {
+				launched++
+				pending++
+				attempt()
+				timer.Reset(b.delay())
+			}
+		}
+	}
+}
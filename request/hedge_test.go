@@ -0,0 +1,267 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-camp/httpc"
+)
+
+type testReadSeeker struct {
+	io.ReadSeeker
+}
+
+func TestHedgeBuilder(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Method string
+		Body   io.Reader
+
+		ExpectAttempts int
+		ExpectOutput   int
+	}{
+		{
+			Name:           "reader at body",
+			Method:         http.MethodGet,
+			Body:           bytes.NewReader([]byte("body")),
+			ExpectAttempts: 2,
+			ExpectOutput:   2,
+		},
+		{
+			Name:           "seeker body",
+			Method:         http.MethodPut,
+			Body:           &testReadSeeker{bytes.NewReader([]byte("body"))},
+			ExpectAttempts: 2,
+			ExpectOutput:   2,
+		},
+		{
+			Name:           "non-seekable body",
+			Method:         http.MethodGet,
+			Body:           bytes.NewBufferString("body"),
+			ExpectAttempts: 1,
+			ExpectOutput:   1,
+		},
+		{
+			Name:           "non-idempotent method",
+			Method:         http.MethodPost,
+			Body:           bytes.NewReader([]byte("body")),
+			ExpectAttempts: 1,
+			ExpectOutput:   1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var mux sync.Mutex
+			var attempts int
+			var canceled bool
+			build := HedgeBuilder{
+				MaxAttempts: 3,
+				Delay:       10 * time.Millisecond,
+			}.Builder(
+				func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+					mux.Lock()
+					attempts++
+					attempt := attempts
+					mux.Unlock()
+
+					body, err := io.ReadAll(req.Body)
+					if err != nil || string(body) != "body" {
+						t.Errorf("expect body is %q, got %q, %v", "body", body, err)
+					}
+
+					if attempt == 1 && tc.ExpectAttempts > 1 {
+						<-req.Context().Done()
+						mux.Lock()
+						canceled = true
+						mux.Unlock()
+						return output, md, req.Context().Err()
+					}
+					return attempt, md, nil
+				},
+			)
+
+			req := &httpc.Request{
+				Request: &http.Request{
+					Method: tc.Method,
+					URL:    &url.URL{},
+					Header: http.Header{},
+				},
+				Body: tc.Body,
+			}
+			output, _, err := build(context.Background(), req)
+			if err != nil {
+				t.Fatalf("expect no err, got %v", err)
+			}
+			if output != tc.ExpectOutput {
+				t.Fatalf("expect output is %d, got %v", tc.ExpectOutput, output)
+			}
+
+			time.Sleep(10 * time.Millisecond)
+			mux.Lock()
+			defer mux.Unlock()
+			if attempts != tc.ExpectAttempts {
+				t.Fatalf("expect %d attempts, got %d", tc.ExpectAttempts, attempts)
+			}
+			if tc.ExpectAttempts > 1 && !canceled {
+				t.Fatalf("expect the first attempt is canceled")
+			}
+		})
+	}
+}
+
+func TestLatencyTracker(t *testing.T) {
+	tracker := &LatencyTracker{Size: 10, MinCount: 5}
+	for i := 1; i <= 4; i++ {
+		tracker.Record(time.Duration(i) * time.Millisecond)
+	}
+	if _, ok := tracker.Percentile(0.5); ok {
+		t.Fatalf("expect not enough latencies")
+	}
+	for i := 5; i <= 20; i++ {
+		tracker.Record(time.Duration(i) * time.Millisecond)
+	}
+
+	testCases := []struct {
+		Percentile float64
+		Expect     time.Duration
+	}{
+		{Percentile: 0.5, Expect: 15 * time.Millisecond},
+		{Percentile: 0.9, Expect: 19 * time.Millisecond},
+		{Percentile: 1, Expect: 20 * time.Millisecond},
+	}
+	for _, tc := range testCases {
+		latency, ok := tracker.Percentile(tc.Percentile)
+		if !ok || latency != tc.Expect {
+			t.Fatalf("expect p%v latency is %s, got %s", tc.Percentile, tc.Expect, latency)
+		}
+	}
+
+	tracker = &LatencyTracker{Size: -1, MinCount: 1}
+	tracker.Record(time.Millisecond)
+	if latency, ok := tracker.Percentile(1); !ok || latency != time.Millisecond {
+		t.Fatalf("expect latency is %s with negative size, got %s", time.Millisecond, latency)
+	}
+}
+
+type testHedgeBody struct {
+	io.Reader
+	closed chan struct{}
+}
+
+func (b *testHedgeBody) Close() error {
+	close(b.closed)
+	return nil
+}
+
+func TestHedgeBuilderResponse(t *testing.T) {
+	var mux sync.Mutex
+	var attempts int
+	var ctxs []context.Context
+	loserBody := &testHedgeBody{Reader: bytes.NewReader(nil), closed: make(chan struct{})}
+	release := make(chan struct{})
+	build := HedgeBuilder{
+		MaxAttempts: 2,
+		Delay:       10 * time.Millisecond,
+	}.Builder(
+		func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+			mux.Lock()
+			attempts++
+			attempt := attempts
+			ctxs = append(ctxs, req.Context())
+			mux.Unlock()
+
+			if attempt == 1 {
+				<-release
+				return &http.Response{StatusCode: http.StatusOK, Body: loserBody}, md, nil
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader([]byte("winner")))}, md, nil
+		},
+	)
+
+	req := &httpc.Request{
+		Request: &http.Request{Method: http.MethodGet, URL: &url.URL{}, Header: http.Header{}},
+	}
+	output, _, err := build(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expect no err, got %v", err)
+	}
+	close(release)
+
+	mux.Lock()
+	winnerCtx, loserCtx := ctxs[1], ctxs[0]
+	mux.Unlock()
+	if loserCtx.Err() == nil {
+		t.Fatalf("expect the losing attempt is canceled")
+	}
+	if err := winnerCtx.Err(); err != nil {
+		t.Fatalf("expect the winning attempt is not canceled before the body is closed, got %v", err)
+	}
+	resp := output.(*http.Response)
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "winner" {
+		t.Fatalf("expect body is winner, got %q", body)
+	}
+	resp.Body.Close()
+	if winnerCtx.Err() == nil {
+		t.Fatalf("expect the winning attempt is canceled after the body is closed")
+	}
+
+	select {
+	case <-loserBody.closed:
+	case <-time.After(time.Second):
+		t.Fatalf("expect the losing response body is closed")
+	}
+}
+
+func TestHedgeBuilderFailedResponse(t *testing.T) {
+	var mux sync.Mutex
+	var attempts int
+	failedBody := &testHedgeBody{Reader: bytes.NewReader(nil), closed: make(chan struct{})}
+	failed := make(chan struct{})
+	build := HedgeBuilder{
+		MaxAttempts: 2,
+		Delay:       10 * time.Millisecond,
+	}.Builder(
+		func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+			mux.Lock()
+			attempts++
+			attempt := attempts
+			mux.Unlock()
+
+			if attempt == 1 {
+				<-failed
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader([]byte("winner")))}, md, nil
+			}
+			defer close(failed)
+			resp := &http.Response{StatusCode: http.StatusServiceUnavailable, Body: failedBody}
+			return output, md, &httpc.ResponseError{Response: resp, Err: io.ErrUnexpectedEOF}
+		},
+	)
+
+	req := &httpc.Request{
+		Request: &http.Request{Method: http.MethodGet, URL: &url.URL{}, Header: http.Header{}},
+	}
+	output, _, err := build(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expect no err, got %v", err)
+	}
+	resp := output.(*http.Response)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "winner" {
+		t.Fatalf("expect body is winner, got %q", body)
+	}
+
+	select {
+	case <-failedBody.closed:
+	default:
+		t.Fatalf("expect the failed response body is closed")
+	}
+}
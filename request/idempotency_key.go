@@ -0,0 +1,148 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-camp/httpc"
+)
+
+type idempotencyKeyKey struct{}
+
+// WithIdempotencyKey returns a copy of ctx with the idempotency key,
+// which is used by IdempotencyKeyBuilder instead of a generated key.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyKey{}, key)
+}
+
+func GetIdempotencyKeyFromContext(ctx context.Context) (key string) {
+	v := ctx.Value(idempotencyKeyKey{})
+	key, _ = v.(string)
+	return
+}
+
+func GetIdempotencyKeyFromMetadata(md httpc.Metadata) (key string) {
+	v := md.Get(idempotencyKeyKey{})
+	key, _ = v.(string)
+	return
+}
+
+// IdempotencyKeyer is implemented by the inputs which carry their own idempotency key.
+type IdempotencyKeyer interface {
+	IdempotencyKey() string
+}
+
+// IdempotencyKeyInitializer adds the idempotency key of the input to context,
+// if the input implements IdempotencyKeyer and the key is not empty.
+type IdempotencyKeyInitializer struct{}
+
+func (ini IdempotencyKeyInitializer) ID() string { return "IdempotencyKeyInitializer" }
+
+func (ini IdempotencyKeyInitializer) Initializer(initialize httpc.InitializeFunc) httpc.InitializeFunc {
+	return func(ctx context.Context, input interface{}) (interface{}, httpc.Metadata, error) {
+		if keyer, ok := input.(IdempotencyKeyer); ok {
+			if key := keyer.IdempotencyKey(); key != "" {
+				ctx = WithIdempotencyKey(ctx, key)
+			}
+		}
+		return initialize(ctx, input)
+	}
+}
+
+var DefaultIdempotencyKeyMethods = []string{http.MethodPost, http.MethodPatch}
+
+// IdempotencyKeyBuilder sets an idempotency key to the value of specified request header.
+//
+// IdempotencyKeyBuilder must be added before RetryBuilder,
+// so that every attempt of the call sends the same key.
+// The key is taken from the request header, the context set by WithIdempotencyKey, or IDGenerator in order,
+// a key is only generated for the requests with a method of Methods.
+// The key is added to metadata.
+type IdempotencyKeyBuilder struct {
+	// default: Idempotency-Key
+	Header string
+	// If IDGenerator is nil, then DefaultIDGenerator is used.
+	IDGenerator func() (string, error)
+	// If Methods is nil, then DefaultIdempotencyKeyMethods is used.
+	Methods []string
+}
+
+type idempotencyKeyError struct {
+	While string
+	Err   error
+}
+
+func (e *idempotencyKeyError) Error() string {
+	return fmt.Sprintf("request idempotency key builder, %s failed, %v", e.While, e.Err)
+}
+
+func (e *idempotencyKeyError) Unwrap() error {
+	return e.Err
+}
+
+func (b IdempotencyKeyBuilder) ID() string { return "IdempotencyKeyBuilder" }
+
+func (b IdempotencyKeyBuilder) Builder(build httpc.BuildFunc) httpc.BuildFunc {
+	return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
+		return b.build(ctx, req, build)
+	}
+}
+
+func (b IdempotencyKeyBuilder) header() string {
+	if b.Header == "" {
+		return headerIdempotencyKey
+	}
+	return b.Header
+}
+
+func (b IdempotencyKeyBuilder) idGenerator() func() (string, error) {
+	if b.IDGenerator == nil {
+		return DefaultIDGenerator
+	}
+	return b.IDGenerator
+}
+
+func (b IdempotencyKeyBuilder) methods() []string {
+	if b.Methods == nil {
+		return DefaultIdempotencyKeyMethods
+	}
+	return b.Methods
+}
+
+func (b IdempotencyKeyBuilder) hasMethod(method string) bool {
+	if method == "" {
+		method = http.MethodGet
+	}
+	for _, m := range b.methods() {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b IdempotencyKeyBuilder) build(ctx context.Context, req *httpc.Request, build httpc.BuildFunc) (
+	output interface{}, md httpc.Metadata, err error,
+) {
+	key := req.Header.Get(b.header())
+	if key == "" {
+		key = GetIdempotencyKeyFromContext(ctx)
+	}
+	if key == "" && b.hasMethod(req.Method) {
+		key, err = b.idGenerator()()
+		if err != nil {
+			err = &idempotencyKeyError{While: "key generate", Err: err}
+			return
+		}
+	}
+	if key == "" {
+		return build(ctx, req)
+	}
+
+	req.Header.Set(b.header(), key)
+	output, md, err = build(ctx, req)
+	md.Set(idempotencyKeyKey{}, key)
+	return
+}
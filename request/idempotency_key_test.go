@@ -0,0 +1,119 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/go-camp/httpc"
+)
+
+type testIdempotencyKeyInput struct {
+	Key string
+}
+
+func (i testIdempotencyKeyInput) IdempotencyKey() string { return i.Key }
+
+func TestIdempotencyKeyBuilder(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Method string
+		Header string
+		Ctx    context.Context
+
+		ExpectKey string
+	}{
+		{
+			Name:      "generated",
+			Method:    http.MethodPost,
+			Ctx:       context.Background(),
+			ExpectKey: "generated-key",
+		},
+		{
+			Name:      "context",
+			Method:    http.MethodPost,
+			Ctx:       WithIdempotencyKey(context.Background(), "context-key"),
+			ExpectKey: "context-key",
+		},
+		{
+			Name:      "header",
+			Method:    http.MethodPost,
+			Header:    "header-key",
+			Ctx:       WithIdempotencyKey(context.Background(), "context-key"),
+			ExpectKey: "header-key",
+		},
+		{
+			Name:   "get",
+			Method: http.MethodGet,
+			Ctx:    context.Background(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var attemptKeys []string
+			build := httpc.ComposeBuilder(
+				IdempotencyKeyBuilder{
+					IDGenerator: func() (string, error) { return "generated-key", nil },
+				}.Builder,
+				RetryBuilder{
+					Retryer: BasicRetryer{Options: BasicRetryerOptions{Delayer: NopRetryDelayer}},
+				}.Builder,
+			)(
+				func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+					attemptKeys = append(attemptKeys, req.Header.Get("Idempotency-Key"))
+					if len(attemptKeys) == 1 {
+						err = &httpc.ResponseError{
+							Response: &http.Response{StatusCode: http.StatusServiceUnavailable},
+							Err:      errors.New("unavailable"),
+						}
+					}
+					return
+				},
+			)
+
+			req := &httpc.Request{
+				Request: &http.Request{Method: tc.Method, URL: &url.URL{}, Header: http.Header{}},
+			}
+			if tc.Header != "" {
+				req.Header.Set("Idempotency-Key", tc.Header)
+			}
+			_, md, err := build(tc.Ctx, req)
+			if err != nil {
+				t.Fatalf("expect no err, got %v", err)
+			}
+
+			if len(attemptKeys) != 2 {
+				t.Fatalf("expect 2 attempts, got %d", len(attemptKeys))
+			}
+			for _, key := range attemptKeys {
+				if key != tc.ExpectKey {
+					t.Fatalf("expect idempotency key is %q, got %q", tc.ExpectKey, key)
+				}
+			}
+			if key := GetIdempotencyKeyFromMetadata(md); key != tc.ExpectKey {
+				t.Fatalf("expect idempotency key of metadata is %q, got %q", tc.ExpectKey, key)
+			}
+		})
+	}
+}
+
+func TestIdempotencyKeyInitializer(t *testing.T) {
+	var key string
+	initialize := IdempotencyKeyInitializer{}.Initializer(
+		func(ctx context.Context, input interface{}) (output interface{}, md httpc.Metadata, err error) {
+			key = GetIdempotencyKeyFromContext(ctx)
+			return
+		},
+	)
+
+	_, _, err := initialize(context.Background(), testIdempotencyKeyInput{Key: "input-key"})
+	if err != nil {
+		t.Fatalf("expect no err, got %v", err)
+	}
+	if key != "input-key" {
+		t.Fatalf("expect idempotency key is input-key, got %q", key)
+	}
+}
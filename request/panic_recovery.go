@@ -0,0 +1,66 @@
+package request
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/go-camp/httpc"
+)
+
+func recoverPanic(stage httpc.Stage, err *error) {
+	if r := recover(); r != nil {
+		*err = &httpc.PanicError{Stage: stage, Value: r, Stack: debug.Stack()}
+	}
+}
+
+// PanicRecoveryInitializer recovers a panic of the wrapped InitializeFunc and returns it as PanicError.
+//
+// PanicRecoveryInitializer should be added after WrapOperationErrorInitializer,
+// so that the PanicError is wrapped as OperationError.
+// A panic which is not recovered by the other PanicRecovery steps is reported as panic in the Initialize stage.
+type PanicRecoveryInitializer struct{}
+
+func (ini PanicRecoveryInitializer) ID() string { return "PanicRecoveryInitializer" }
+
+func (ini PanicRecoveryInitializer) Initializer(initialize httpc.InitializeFunc) httpc.InitializeFunc {
+	return func(ctx context.Context, input interface{}) (output interface{}, md httpc.Metadata, err error) {
+		defer recoverPanic(httpc.StageInitialize, &err)
+		return initialize(ctx, input)
+	}
+}
+
+// PanicRecoverySerializer recovers a panic of the wrapped SerializeFunc and returns it as PanicError.
+type PanicRecoverySerializer struct{}
+
+func (s PanicRecoverySerializer) ID() string { return "PanicRecoverySerializer" }
+
+func (s PanicRecoverySerializer) Serializer(serialize httpc.SerializeFunc) httpc.SerializeFunc {
+	return func(ctx context.Context, input httpc.SerializeInput) (output interface{}, md httpc.Metadata, err error) {
+		defer recoverPanic(httpc.StageSerialize, &err)
+		return serialize(ctx, input)
+	}
+}
+
+// PanicRecoveryBuilder recovers a panic of the wrapped BuildFunc and returns it as PanicError.
+type PanicRecoveryBuilder struct{}
+
+func (b PanicRecoveryBuilder) ID() string { return "PanicRecoveryBuilder" }
+
+func (b PanicRecoveryBuilder) Builder(build httpc.BuildFunc) httpc.BuildFunc {
+	return func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+		defer recoverPanic(httpc.StageBuild, &err)
+		return build(ctx, req)
+	}
+}
+
+// PanicRecoveryFinalizer recovers a panic of the wrapped FinalizeFunc and returns it as PanicError.
+type PanicRecoveryFinalizer struct{}
+
+func (f PanicRecoveryFinalizer) ID() string { return "PanicRecoveryFinalizer" }
+
+func (f PanicRecoveryFinalizer) Finalizer(finalize httpc.FinalizeFunc) httpc.FinalizeFunc {
+	return func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+		defer recoverPanic(httpc.StageFinalize, &err)
+		return finalize(ctx, req)
+	}
+}
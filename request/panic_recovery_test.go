@@ -0,0 +1,71 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-camp/httpc"
+)
+
+func TestPanicRecovery(t *testing.T) {
+	testCases := []struct {
+		Name       string
+		Serializer httpc.Serializer
+
+		ExpectStage httpc.Stage
+	}{
+		{
+			Name:        "initialize stage",
+			ExpectStage: httpc.StageInitialize,
+		},
+		{
+			Name:        "serialize stage",
+			Serializer:  PanicRecoverySerializer{}.Serializer,
+			ExpectStage: httpc.StageSerialize,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			stack := &httpc.Stack{}
+			stack.Initialize.Add("Name", ServiceOperationNameInitializer{
+				ServiceName:   "service",
+				OperationName: "operation",
+			}.Initializer, httpc.After)
+			stack.Initialize.Add("Wrap", WrapOperationErrorInitializer{}.Initializer, httpc.After)
+			stack.Initialize.Add("Recovery", PanicRecoveryInitializer{}.Initializer, httpc.After)
+			if tc.Serializer != nil {
+				stack.Serialize.Add("Recovery", tc.Serializer, httpc.After)
+			}
+			stack.Serialize.Add("Panic", func(serialize httpc.SerializeFunc) httpc.SerializeFunc {
+				return func(ctx context.Context, input httpc.SerializeInput) (interface{}, httpc.Metadata, error) {
+					panic("serialize panic")
+				}
+			}, httpc.After)
+
+			_, _, err := httpc.Handler{Stack: stack}.Handle(context.Background(), nil)
+
+			var opErr *httpc.OperationError
+			if !errors.As(err, &opErr) {
+				t.Fatalf("expect err is %T, got %v", opErr, err)
+			}
+			if opErr.Service != "service" || opErr.Operation != "operation" {
+				t.Fatalf("expect service operation names, got %s %s", opErr.Service, opErr.Operation)
+			}
+			var panicErr *httpc.PanicError
+			if !errors.As(err, &panicErr) {
+				t.Fatalf("expect err is %T, got %v", panicErr, err)
+			}
+			if panicErr.Stage != tc.ExpectStage {
+				t.Fatalf("expect stage is %s, got %s", tc.ExpectStage, panicErr.Stage)
+			}
+			if panicErr.Value != "serialize panic" {
+				t.Fatalf("expect panic value is %q, got %v", "serialize panic", panicErr.Value)
+			}
+			if len(panicErr.Stack) == 0 {
+				t.Fatalf("expect panic stack, got none")
+			}
+		})
+	}
+}
@@ -37,6 +37,8 @@ func (e *requestIDError) Unwrap() error {
 	return e.Err
 }
 
+func (b RequestIDBuilder) ID() string { return "RequestIDBuilder" }
+
 func (b RequestIDBuilder) Builder(build httpc.BuildFunc) httpc.BuildFunc {
 	return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
 		return b.build(ctx, req, build)
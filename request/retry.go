@@ -8,10 +8,12 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-camp/httpc"
+	"github.com/go-camp/httpc/response"
 	"github.com/go-camp/retry"
 )
 
@@ -80,9 +82,12 @@ func (r RetryableConnectionErrorChecker) Check(err error) Retryable {
 
 	var tempErr interface{ Temporary() bool }
 	var timeoutErr interface{ Timeout() bool }
+	var attemptTimeoutErr *AttemptTimeoutError
 	var urlErr *url.Error
 	var netOpErr *net.OpError
 	switch {
+	case errors.As(err, &attemptTimeoutErr):
+		return RetryableYes
 	case strings.Contains(err.Error(), "connection reset"):
 		return RetryableYes
 	case errors.As(err, &urlErr):
@@ -123,6 +128,7 @@ func (r RetryableHTTPStatusCodeChecker) Check(err error) Retryable {
 }
 
 var DefaultRetryableHTTPStatusCodes = map[int]struct{}{
+	http.StatusTooManyRequests:     {},
 	http.StatusInternalServerError: {},
 	http.StatusBadGateway:          {},
 	http.StatusServiceUnavailable:  {},
@@ -218,35 +224,134 @@ var DefaultRetryer Retryer = BasicRetryer{
 	},
 }
 
+const DefaultRetryMaxRetryAfter = 20 * time.Second
+
+// RetryBuilder retries the rest of the call chain according to Retryer.
+//
+// If the Retryer implements RequestRetryer, such as RetryPolicy,
+// the retryable check and the maximum number of attempts depend on the request and the error.
+//
+// RetryBuilder should be the last Builder,
+// so that every Builder runs once per call and every Finalizer runs once per attempt.
+// The attempt number can be got by GetAttemptFromContext,
+// from the context of a Finalizer or the request context of a Deserializer.
+// The request Body must implement io.Seeker to be retried,
+// a non-seekable Body can be made seekable by BodyBufferBuilder.
+//
+// If the error is a ResponseError with a Retry-After header,
+// the delay from the header is used instead of the Retryer's delay.
+// An HTTP-date value is interpreted against the response date set by DateDeserializer,
+// or the local time if the response date is absent.
 type RetryBuilder struct {
 	Retryer Retryer
+	// MaxRetryAfter caps the delay from the Retry-After header.
+	// If MaxRetryAfter is 0, then DefaultRetryMaxRetryAfter is used.
+	// If MaxRetryAfter is negative, the Retry-After header is ignored.
+	MaxRetryAfter time.Duration
+	// TokenBucket is the retry quota, it is usually shared by the RetryBuilders of a client.
+	// If TokenBucket is nil, retries are not limited by quota.
+	TokenBucket *RetryTokenBucket
+	// AttemptTimeout is the timeout of every attempt.
+	// If an attempt times out, the error is wrapped as AttemptTimeoutError.
+	// Like http.Client.Timeout, it also covers reading the response body after the call returns,
+	// the request context of the attempt is released when the response body is closed.
+	// If AttemptTimeout is 0, the attempts are only limited by the deadline of the call.
+	AttemptTimeout time.Duration
+	// Sleep waits the delay between attempts, it returns an error if ctx is done first.
+	// If Sleep is nil, a timer is used.
+	Sleep func(ctx context.Context, d time.Duration) error
+}
+
+// AttemptTimeoutError is returned when an attempt exceeds RetryBuilder.AttemptTimeout.
+type AttemptTimeoutError struct {
+	Duration time.Duration
+	Err      error
+}
+
+func (e *AttemptTimeoutError) Error() string {
+	return fmt.Sprintf("request attempt timeout %s exceeded, %v", e.Duration, e.Err)
+}
+
+func (e *AttemptTimeoutError) Unwrap() error {
+	return e.Err
 }
 
-func (d RetryBuilder) retryer() Retryer {
+func (e *AttemptTimeoutError) Timeout() bool {
+	return true
+}
+
+func (d RetryBuilder) retryer(ctx context.Context) Retryer {
+	if retryer, ok := ctx.Value(retryerKey{}).(Retryer); ok {
+		return retryer
+	}
 	if d.Retryer == nil {
 		return DefaultRetryer
 	}
 	return d.Retryer
 }
 
-func (d RetryBuilder) Builder(build httpc.BuildFunc) httpc.BuildFunc {
-	return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
-		return d.build(ctx, req, build)
+func (d RetryBuilder) sleep(ctx context.Context, delay time.Duration) error {
+	if d.Sleep == nil {
+		return sleep(ctx, delay)
 	}
+	return d.Sleep(ctx, delay)
 }
 
-type retryError struct {
-	Attempts int
-	Message  string
-	Err      error
+func (d RetryBuilder) maxRetryAfter() time.Duration {
+	if d.MaxRetryAfter == 0 {
+		return DefaultRetryMaxRetryAfter
+	}
+	return d.MaxRetryAfter
 }
 
-func (e *retryError) Error() string {
-	return fmt.Sprintf("request retry finalizer, attempts %d, %s, %v", e.Attempts, e.Message, e.Err)
+// retryAfter returns the delay from the Retry-After header of the ResponseError.
+func (d RetryBuilder) retryAfter(err error, md httpc.Metadata) (delay time.Duration, ok bool) {
+	max := d.maxRetryAfter()
+	if max < 0 {
+		return
+	}
+	var respErr *httpc.ResponseError
+	if !errors.As(err, &respErr) || respErr.Response == nil {
+		return
+	}
+	v := strings.TrimSpace(respErr.Response.Header.Get(headerRetryAfter))
+	if v == "" {
+		return
+	}
+
+	if seconds, perr := strconv.ParseInt(v, 10, 64); perr == nil {
+		if seconds < 0 {
+			return
+		}
+		if seconds > int64(max/time.Second) {
+			return max, true
+		}
+		delay = time.Duration(seconds) * time.Second
+	} else if t, perr := http.ParseTime(v); perr == nil {
+		now := response.GetDate(md)
+		if now.IsZero() {
+			now = time.Now()
+		}
+		delay = t.Sub(now)
+		if delay < 0 {
+			delay = 0
+		}
+	} else {
+		return
+	}
+
+	if delay > max {
+		delay = max
+	}
+	return delay, true
 }
 
-func (e *retryError) Unwrap() error {
-	return e.Err
+func (d RetryBuilder) ID() string { return "RetryBuilder" }
+
+func (d RetryBuilder) Builder(build httpc.BuildFunc) httpc.BuildFunc {
+	return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
+		return d.build(ctx, req, build)
+	}
 }
 
 func sleep(ctx context.Context, d time.Duration) error {
@@ -260,6 +365,31 @@ func sleep(ctx context.Context, d time.Duration) error {
 	}
 }
 
+// attempt calls finalize with the attempt number in both ctx and the request context.
+func (d RetryBuilder) attempt(ctx context.Context, req *httpc.Request, finalize httpc.BuildFunc, attempt, maxAttempts int) (
+	output interface{}, md httpc.Metadata, err error,
+) {
+	if d.AttemptTimeout <= 0 {
+		reqCtx := withAttempt(req.Context(), attempt, maxAttempts)
+		return finalize(withAttempt(ctx, attempt, maxAttempts), req.Clone(reqCtx))
+	}
+
+	deadline := time.Now().Add(d.AttemptTimeout)
+	attemptCtx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	reqCtx, reqCancel := context.WithDeadline(req.Context(), deadline)
+
+	attemptReq := req.Clone(withAttempt(reqCtx, attempt, maxAttempts))
+	output, md, err = finalize(withAttempt(attemptCtx, attempt, maxAttempts), attemptReq)
+	// the response body may be read after return, the request context is released when it is closed.
+	cancelOnClose(output, md, err, reqCancel)
+	if err != nil && ctx.Err() == nil && req.Context().Err() == nil &&
+		(attemptCtx.Err() == context.DeadlineExceeded || reqCtx.Err() == context.DeadlineExceeded) {
+		err = &AttemptTimeoutError{Duration: d.AttemptTimeout, Err: err}
+	}
+	return
+}
+
 func (d RetryBuilder) build(ctx context.Context, req *httpc.Request, finalize httpc.BuildFunc) (
 	output interface{}, md httpc.Metadata, err error,
 ) {
@@ -271,7 +401,7 @@ func (d RetryBuilder) build(ctx context.Context, req *httpc.Request, finalize ht
 		var rr *rewindReader
 		rr, err = newRewindReader(body)
 		if err != nil {
-			err = &retryError{Message: "new rewind reader failed", Err: err}
+			err = &BodyRewindError{Err: fmt.Errorf("new rewind reader failed, %w", err)}
 			return
 		}
 		rewind = func() error {
@@ -283,46 +413,117 @@ func (d RetryBuilder) build(ctx context.Context, req *httpc.Request, finalize ht
 		}
 	}
 
-	retryer := d.retryer()
+	retryer := d.retryer(ctx)
+	// the retryer of WithRetryer is not used by the nested calls with the context of this call.
+	if ctx.Value(retryerKey{}) != nil {
+		ctx = context.WithValue(ctx, retryerKey{}, nil)
+	}
+	if reqCtx := req.Context(); reqCtx.Value(retryerKey{}) != nil {
+		req = req.Clone(context.WithValue(reqCtx, retryerKey{}, nil))
+	}
 	maxAttempts := retryer.MaxAttempts()
 	attempts := 1
 	var delay time.Duration
+	var retryCost int
+	var rm RetryMetadata
+	defer func() {
+		md.Set(mdRetryKey{}, rm)
+	}()
+	rateLimiter, _ := retryer.(AttemptRateLimiter)
+	reqRetryer, _ := retryer.(RequestRetryer)
 	for {
-		output, md, err = finalize(ctx, req.Clone(req.Context()))
+		if rateLimiter != nil {
+			if err = rateLimiter.WaitAttempt(ctx); err != nil {
+				err = &RetryCanceledError{Attempts: attempts - 1, While: "rate limit wait", Err: err}
+				return
+			}
+		}
+		start := time.Now()
+		output, md, err = d.attempt(ctx, req, finalize, attempts, maxAttempts)
+		rm.Attempts = append(rm.Attempts, newAttemptMetadata(attempts, start, md, err))
+		if rateLimiter != nil {
+			rateLimiter.RecordAttempt(err)
+		}
 		if err == nil {
+			if d.TokenBucket != nil {
+				d.TokenBucket.Release(retryCost)
+			}
 			return
 		}
-		if maxAttempts > 0 && attempts >= maxAttempts {
-			err = &retryError{
-				Attempts: attempts,
-				Message:  fmt.Sprintf("max attempts %d exhausted", maxAttempts),
-				Err:      err,
-			}
+		if ctx.Err() != nil {
+			err = &RetryCanceledError{Attempts: attempts, While: "attempt", Err: err}
 			return
 		}
-		retryable := retryer.Check(err)
+		var retryable Retryable
+		if reqRetryer != nil {
+			// the maximum of the next attempt is the one for the error of this attempt.
+			retryable, maxAttempts = reqRetryer.CheckRequest(req, err)
+		} else {
+			retryable = retryer.Check(err)
+		}
 		if retryable != RetryableYes {
-			err = &retryError{
+			err = &NonRetryableError{
 				Attempts: attempts,
-				Message:  fmt.Sprintf("retryable %s", retryable),
+				Check:    retryable,
 				Err:      err,
 			}
 			return
 		}
+		if maxAttempts > 0 && attempts >= maxAttempts {
+			err = &MaxAttemptsExceededError{
+				Attempts:    attempts,
+				MaxAttempts: maxAttempts,
+				Err:         err,
+			}
+			return
+		}
 
-		attempts++
-		delay = retryer.Delay(attempts)
-		if err = sleep(ctx, delay); err != nil {
-			err = &retryError{
+		if d.TokenBucket != nil {
+			var ok bool
+			if retryCost, ok = d.TokenBucket.Acquire(err); !ok {
+				err = &QuotaExceededError{Attempts: attempts, Err: err}
+				return
+			}
+		}
+
+		var ok bool
+		if delay, ok = d.retryAfter(err, md); !ok {
+			delay = retryer.Delay(attempts + 1)
+		}
+		rm.Attempts[len(rm.Attempts)-1].Delay = delay
+		if err = d.sleep(ctx, delay); err != nil {
+			err = &RetryCanceledError{
 				Attempts: attempts,
-				Message:  "sleep canceled",
+				While:    "sleep",
 				Err:      err,
 			}
 			return
 		}
 		if err = rewind(); err != nil {
-			err = &retryError{Attempts: attempts, Message: "body rewind failed", Err: err}
+			err = &BodyRewindError{Attempts: attempts, Err: err}
 			return
 		}
+		attempts++
+	}
+}
+
+type retryerKey struct{}
+
+// WithRetryer makes the RetryBuilder of the current call use retryer instead of its Retryer.
+// The retryer is passed by the context, which is set by an Initialize step with ID "RetryerInitializer",
+// so it works whether the RetryBuilder is in Handler.Builder or Stack.
+// WithRetryer has no effect if the handler has no RetryBuilder,
+// and the retryer is not passed to the calls made by the steps after RetryBuilder.
+func WithRetryer(retryer Retryer) func(*httpc.Options) {
+	return func(o *httpc.Options) {
+		const id = "RetryerInitializer"
+		step := func(initialize httpc.InitializeFunc) httpc.InitializeFunc {
+			return func(ctx context.Context, input interface{}) (interface{}, httpc.Metadata, error) {
+				return initialize(context.WithValue(ctx, retryerKey{}, retryer), input)
+			}
+		}
+		if _, err := o.Stack.Initialize.Swap(id, step); err != nil {
+			o.Stack.Initialize.Add(id, step, httpc.Before)
+		}
 	}
 }
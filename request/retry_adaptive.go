@@ -0,0 +1,253 @@
+package request
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-camp/httpc"
+)
+
+// AttemptRateLimiter is an optional interface of Retryer.
+// RetryBuilder calls WaitAttempt before every attempt and RecordAttempt after every attempt.
+type AttemptRateLimiter interface {
+	// WaitAttempt blocks until the attempt can be sent or ctx is done.
+	WaitAttempt(ctx context.Context) error
+
+	// RecordAttempt records the result of the attempt.
+	RecordAttempt(err error)
+}
+
+var DefaultThrottleHTTPStatusCodes = map[int]struct{}{
+	http.StatusTooManyRequests: {},
+}
+
+// DefaultThrottleChecker treats 429 responses as throttling.
+// Throttling error codes can be detected by adding a RetryableErrorCodeChecker.
+var DefaultThrottleChecker = RetryableCheckers{
+	RetryableHTTPStatusCodeChecker{
+		Codes: DefaultThrottleHTTPStatusCodes,
+	},
+}
+
+const (
+	rateLimiterSmooth        = 0.8
+	rateLimiterBeta          = 0.7
+	rateLimiterScaleConstant = 0.4
+	rateLimiterMinFillRate   = 0.5
+	rateLimiterMinCapacity   = 1
+)
+
+// ClientRateLimiter is a client-side sending rate limiter using the cubic algorithm.
+//
+// The limiter is disabled until the first throttling response,
+// then the sending rate is lowered whenever a throttling response is received
+// and recovers as calls succeed.
+type ClientRateLimiter struct {
+	// If Now is nil, then time.Now is used.
+	Now func() time.Time
+
+	mux sync.Mutex
+
+	enabled         bool
+	fillRate        float64
+	maxCapacity     float64
+	currentCapacity float64
+	lastRefilled    time.Time
+
+	measuredTxRate   float64
+	lastTxRateBucket float64
+	requestCount     int64
+
+	lastMaxRate      float64
+	lastThrottleTime time.Time
+	timeWindow       float64
+}
+
+func (l *ClientRateLimiter) now() time.Time {
+	if l.Now == nil {
+		return time.Now()
+	}
+	return l.Now()
+}
+
+func seconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+func (l *ClientRateLimiter) refill(now time.Time) {
+	if l.lastRefilled.IsZero() {
+		l.lastRefilled = now
+		return
+	}
+	amount := now.Sub(l.lastRefilled).Seconds() * l.fillRate
+	l.currentCapacity = math.Min(l.maxCapacity, l.currentCapacity+amount)
+	l.lastRefilled = now
+}
+
+// acquire takes one token, wait is the duration to wait before trying again if ok is false.
+func (l *ClientRateLimiter) acquire() (ok bool, wait time.Duration) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if !l.enabled {
+		return true, 0
+	}
+	l.refill(l.now())
+	if l.currentCapacity >= 1 {
+		l.currentCapacity--
+		return true, 0
+	}
+	return false, time.Duration(math.Ceil((1 - l.currentCapacity) / l.fillRate * float64(time.Second)))
+}
+
+// Wait blocks until a token is available or ctx is done.
+func (l *ClientRateLimiter) Wait(ctx context.Context) error {
+	for {
+		ok, wait := l.acquire()
+		if ok {
+			return nil
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// Rate returns the current sending rate in requests per second.
+// Rate returns +Inf if the limiter is disabled.
+func (l *ClientRateLimiter) Rate() float64 {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if !l.enabled {
+		return math.Inf(1)
+	}
+	return l.fillRate
+}
+
+// Update updates the sending rate with the result of an attempt.
+func (l *ClientRateLimiter) Update(throttled bool) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	now := l.now()
+	if l.lastThrottleTime.IsZero() {
+		l.lastThrottleTime = now
+	}
+	l.updateMeasuredRate(now)
+
+	var rate float64
+	if throttled {
+		rateToUse := l.measuredTxRate
+		if l.enabled {
+			rateToUse = math.Min(l.measuredTxRate, l.fillRate)
+		}
+		l.lastMaxRate = rateToUse
+		l.calculateTimeWindow()
+		l.lastThrottleTime = now
+		rate = rateToUse * rateLimiterBeta
+		l.enabled = true
+	} else {
+		l.calculateTimeWindow()
+		dt := now.Sub(l.lastThrottleTime).Seconds()
+		rate = rateLimiterScaleConstant*math.Pow(dt-l.timeWindow, 3) + l.lastMaxRate
+	}
+
+	l.updateRate(math.Min(rate, 2*l.measuredTxRate), now)
+}
+
+func (l *ClientRateLimiter) calculateTimeWindow() {
+	l.timeWindow = math.Cbrt(l.lastMaxRate * (1 - rateLimiterBeta) / rateLimiterScaleConstant)
+}
+
+func (l *ClientRateLimiter) updateMeasuredRate(now time.Time) {
+	bucket := math.Floor(seconds(now)*2) / 2
+	l.requestCount++
+	if l.lastTxRateBucket == 0 {
+		l.lastTxRateBucket = bucket
+		return
+	}
+	if bucket > l.lastTxRateBucket {
+		currentRate := float64(l.requestCount) / (bucket - l.lastTxRateBucket)
+		l.measuredTxRate = currentRate*rateLimiterSmooth + l.measuredTxRate*(1-rateLimiterSmooth)
+		l.requestCount = 0
+		l.lastTxRateBucket = bucket
+	}
+}
+
+func (l *ClientRateLimiter) updateRate(rate float64, now time.Time) {
+	l.refill(now)
+	l.fillRate = math.Max(rate, rateLimiterMinFillRate)
+	l.maxCapacity = math.Max(rate, rateLimiterMinCapacity)
+	l.currentCapacity = math.Min(l.currentCapacity, l.maxCapacity)
+}
+
+// AdaptiveRetryer is a Retryer which limits the sending rate by ClientRateLimiter.
+//
+// Every attempt waits for a token from RateLimiter,
+// and the result of every attempt is checked by ThrottleChecker to update the sending rate.
+// If Retryer implements RequestRetryer, such as RetryPolicy, CheckRequest is delegated to it.
+type AdaptiveRetryer struct {
+	// If Retryer is nil, then DefaultRetryer is used.
+	Retryer Retryer
+	// RateLimiter is usually shared by the calls of a client.
+	RateLimiter *ClientRateLimiter
+	// If ThrottleChecker is nil, then DefaultThrottleChecker is used.
+	ThrottleChecker RetryableChecker
+}
+
+var (
+	_ AttemptRateLimiter = AdaptiveRetryer{}
+	_ RequestRetryer     = AdaptiveRetryer{}
+)
+
+func (r AdaptiveRetryer) retryer() Retryer {
+	if r.Retryer == nil {
+		return DefaultRetryer
+	}
+	return r.Retryer
+}
+
+func (r AdaptiveRetryer) throttleChecker() RetryableChecker {
+	if r.ThrottleChecker == nil {
+		return DefaultThrottleChecker
+	}
+	return r.ThrottleChecker
+}
+
+func (r AdaptiveRetryer) MaxAttempts() int {
+	return r.retryer().MaxAttempts()
+}
+
+func (r AdaptiveRetryer) Delay(attempt int) time.Duration {
+	return r.retryer().Delay(attempt)
+}
+
+func (r AdaptiveRetryer) Check(err error) Retryable {
+	return r.retryer().Check(err)
+}
+
+func (r AdaptiveRetryer) CheckRequest(req *httpc.Request, err error) (retryable Retryable, maxAttempts int) {
+	retryer := r.retryer()
+	if reqRetryer, ok := retryer.(RequestRetryer); ok {
+		return reqRetryer.CheckRequest(req, err)
+	}
+	return retryer.Check(err), retryer.MaxAttempts()
+}
+
+func (r AdaptiveRetryer) WaitAttempt(ctx context.Context) error {
+	if r.RateLimiter == nil {
+		return nil
+	}
+	return r.RateLimiter.Wait(ctx)
+}
+
+func (r AdaptiveRetryer) RecordAttempt(err error) {
+	if r.RateLimiter == nil {
+		return
+	}
+	throttled := err != nil && r.throttleChecker().Check(err) == RetryableYes
+	r.RateLimiter.Update(throttled)
+}
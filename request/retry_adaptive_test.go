@@ -0,0 +1,107 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-camp/httpc"
+)
+
+func TestClientRateLimiter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := &ClientRateLimiter{Now: func() time.Time { return now }}
+
+	// 10 requests per second without throttling.
+	for i := 0; i < 20; i++ {
+		l.Update(false)
+		now = now.Add(100 * time.Millisecond)
+	}
+	if rate := l.Rate(); !math.IsInf(rate, 1) {
+		t.Fatalf("expect limiter is disabled, got rate %f", rate)
+	}
+	if ok, _ := l.acquire(); !ok {
+		t.Fatalf("expect acquire succeeded while disabled")
+	}
+
+	l.Update(true)
+	throttledRate := l.Rate()
+	if throttledRate >= 10 || throttledRate < rateLimiterMinFillRate {
+		t.Fatalf("expect rate is lowered below 10, got %f", throttledRate)
+	}
+	var ok bool
+	var wait time.Duration
+	for i := 0; i <= int(throttledRate)+1; i++ {
+		if ok, wait = l.acquire(); !ok {
+			break
+		}
+	}
+	if ok || wait <= 0 {
+		t.Fatalf("expect acquire failed with wait, got %v, %s", ok, wait)
+	}
+	now = now.Add(wait)
+	if ok, _ = l.acquire(); !ok {
+		t.Fatalf("expect acquire succeeded after wait")
+	}
+
+	for i := 0; i < 40; i++ {
+		now = now.Add(100 * time.Millisecond)
+		l.Update(false)
+	}
+	if rate := l.Rate(); rate <= throttledRate {
+		t.Fatalf("expect rate recovers above %f, got %f", throttledRate, rate)
+	}
+}
+
+func TestRetryBuilderAdaptiveRetryer(t *testing.T) {
+	now := time.Unix(1000, 0)
+	limiter := &ClientRateLimiter{
+		Now: func() time.Time {
+			now = now.Add(100 * time.Millisecond)
+			return now
+		},
+	}
+	for i := 0; i < 20; i++ {
+		limiter.Update(false)
+	}
+	var buildCount int
+	build := RetryBuilder{
+		Retryer: AdaptiveRetryer{
+			Retryer: BasicRetryer{
+				Options: BasicRetryerOptions{Delayer: NopRetryDelayer},
+			},
+			RateLimiter: limiter,
+		},
+	}.Builder(
+		func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+			buildCount++
+			if buildCount == 1 {
+				err = &httpc.ResponseError{
+					Response: &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}},
+					Err:      errors.New("throttled"),
+				}
+			}
+			return
+		},
+	)
+
+	req := &httpc.Request{
+		Request: &http.Request{URL: &url.URL{}, Header: http.Header{}},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, _, err := build(ctx, req)
+	if err != nil {
+		t.Fatalf("expect no err, got %v", err)
+	}
+	if buildCount != 2 {
+		t.Fatalf("expect 2 attempts, got %d", buildCount)
+	}
+	if rate := limiter.Rate(); math.IsInf(rate, 1) {
+		t.Fatalf("expect limiter is enabled after throttling")
+	}
+}
@@ -0,0 +1,156 @@
+package request
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/go-camp/retry"
+)
+
+const (
+	DefaultRetryBaseDelay = 1 * time.Second
+	DefaultRetryMaxDelay  = 20 * time.Second
+)
+
+// WithRetryDelayer sets the Delayer of BasicRetryerOptions to d.Delay.
+// d can be any delayer of this package or github.com/go-camp/retry,
+// such as retry.ConstantDelayer for a constant delay.
+//
+// The delayers only calculate the delays, the waits between attempts are done by RetryBuilder.Sleep,
+// which replaces the clock in deterministic tests.
+func WithRetryDelayer(d retry.Delayer) func(*BasicRetryerOptions) {
+	return func(o *BasicRetryerOptions) {
+		o.Delayer = d.Delay
+	}
+}
+
+func delayBase(base time.Duration) time.Duration {
+	if base <= 0 {
+		return DefaultRetryBaseDelay
+	}
+	return base
+}
+
+func delayMax(max time.Duration) time.Duration {
+	if max <= 0 {
+		return DefaultRetryMaxDelay
+	}
+	return max
+}
+
+func delayRandom(random func() float64) func() float64 {
+	if random == nil {
+		return rand.Float64
+	}
+	return random
+}
+
+// expDelay returns min(max, base*2^(attempt-1)).
+func expDelay(base, max time.Duration, attempt int) time.Duration {
+	d := float64(base) * math.Pow(2, float64(attempt-1))
+	if d >= float64(max) {
+		return max
+	}
+	return time.Duration(d)
+}
+
+// FullJitterDelayer returns a random delay in [0, min(Max, Base*2^(attempt-1))).
+type FullJitterDelayer struct {
+	// If Base is 0, then DefaultRetryBaseDelay is used.
+	Base time.Duration
+	// If Max is 0, then DefaultRetryMaxDelay is used.
+	Max time.Duration
+	// Random returns a random number in [0.0,1.0).
+	// If Random is nil, then math/rand.Float64 is used.
+	Random func() float64
+}
+
+func (d FullJitterDelayer) Delay(attempt int) time.Duration {
+	if attempt <= 0 {
+		return 0
+	}
+	exp := expDelay(delayBase(d.Base), delayMax(d.Max), attempt)
+	return time.Duration(delayRandom(d.Random)() * float64(exp))
+}
+
+// EqualJitterDelayer returns a random delay in [exp/2, exp), exp is min(Max, Base*2^(attempt-1)).
+type EqualJitterDelayer struct {
+	// If Base is 0, then DefaultRetryBaseDelay is used.
+	Base time.Duration
+	// If Max is 0, then DefaultRetryMaxDelay is used.
+	Max time.Duration
+	// Random returns a random number in [0.0,1.0).
+	// If Random is nil, then math/rand.Float64 is used.
+	Random func() float64
+}
+
+func (d EqualJitterDelayer) Delay(attempt int) time.Duration {
+	if attempt <= 0 {
+		return 0
+	}
+	half := expDelay(delayBase(d.Base), delayMax(d.Max), attempt) / 2
+	return half + time.Duration(delayRandom(d.Random)()*float64(half))
+}
+
+// DecorrelatedJitterDelayer returns min(Max, random_between(Base, previous*3)),
+// the previous delay of the first attempt is Base.
+//
+// A Delayer only gets the attempt number, so DecorrelatedJitterDelayer keeps no state between the attempts of a call,
+// the previous delays are recalculated with new random numbers on every call of Delay.
+// Hence the delay is not based on the previous delay actually waited, e.g. a delay from the Retry-After header,
+// and Delay draws attempt-1 random numbers.
+// The distribution of every delay is the same as decorrelated jitter with state.
+type DecorrelatedJitterDelayer struct {
+	// If Base is 0, then DefaultRetryBaseDelay is used.
+	Base time.Duration
+	// If Max is 0, then DefaultRetryMaxDelay is used.
+	Max time.Duration
+	// Random returns a random number in [0.0,1.0).
+	// If Random is nil, then math/rand.Float64 is used.
+	Random func() float64
+}
+
+func (d DecorrelatedJitterDelayer) Delay(attempt int) time.Duration {
+	if attempt <= 0 {
+		return 0
+	}
+	base, max, random := delayBase(d.Base), delayMax(d.Max), delayRandom(d.Random)
+	delay := base
+	for i := 1; i < attempt; i++ {
+		delay = base + time.Duration(random()*float64(delay*3-base))
+		if delay >= max {
+			delay = max
+		}
+	}
+	return delay
+}
+
+// LinearDelayer returns min(Max, Initial+Increment*(attempt-1)).
+type LinearDelayer struct {
+	// If Initial is 0, then DefaultRetryBaseDelay is used.
+	Initial time.Duration
+	// If Increment is 0, then Initial is used.
+	Increment time.Duration
+	// If Max is 0, then DefaultRetryMaxDelay is used.
+	Max time.Duration
+}
+
+func (d LinearDelayer) Delay(attempt int) time.Duration {
+	if attempt <= 0 {
+		return 0
+	}
+	initial, max := delayBase(d.Initial), delayMax(d.Max)
+	increment := d.Increment
+	if increment == 0 {
+		increment = initial
+	}
+	delay := float64(initial) + float64(increment)*float64(attempt-1)
+	if delay >= float64(max) {
+		return max
+	}
+	if delay < 0 {
+		return 0
+	}
+	return time.Duration(delay)
+}
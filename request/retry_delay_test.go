@@ -0,0 +1,130 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-camp/httpc"
+	"github.com/go-camp/retry"
+)
+
+func TestDelayers(t *testing.T) {
+	half := func() float64 { return 0.5 }
+	testCases := []struct {
+		Name    string
+		Delayer retry.Delayer
+
+		ExpectDelays map[int]time.Duration
+	}{
+		{
+			Name:    "full_jitter",
+			Delayer: FullJitterDelayer{Random: half},
+			ExpectDelays: map[int]time.Duration{
+				0:  0,
+				1:  500 * time.Millisecond,
+				3:  2 * time.Second,
+				10: 10 * time.Second,
+			},
+		},
+		{
+			Name:    "equal_jitter",
+			Delayer: EqualJitterDelayer{Random: half},
+			ExpectDelays: map[int]time.Duration{
+				0:  0,
+				1:  750 * time.Millisecond,
+				3:  3 * time.Second,
+				10: 15 * time.Second,
+			},
+		},
+		{
+			Name:    "decorrelated_jitter",
+			Delayer: DecorrelatedJitterDelayer{Random: half},
+			ExpectDelays: map[int]time.Duration{
+				0:  0,
+				1:  1 * time.Second,
+				2:  2 * time.Second,
+				3:  3500 * time.Millisecond,
+				10: 20 * time.Second,
+			},
+		},
+		{
+			Name:    "linear",
+			Delayer: LinearDelayer{Initial: time.Second, Increment: 500 * time.Millisecond, Max: 3 * time.Second},
+			ExpectDelays: map[int]time.Duration{
+				0:  0,
+				1:  1 * time.Second,
+				3:  2 * time.Second,
+				10: 3 * time.Second,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			for attempt, expectDelay := range tc.ExpectDelays {
+				if delay := tc.Delayer.Delay(attempt); delay != expectDelay {
+					t.Fatalf("expect delay of attempt %d is %s, got %s", attempt, expectDelay, delay)
+				}
+			}
+		})
+	}
+}
+
+func TestDelayersRandomRange(t *testing.T) {
+	delayers := []retry.Delayer{
+		FullJitterDelayer{},
+		EqualJitterDelayer{},
+		DecorrelatedJitterDelayer{},
+	}
+	for _, delayer := range delayers {
+		for attempt := 1; attempt <= 10; attempt++ {
+			delay := delayer.Delay(attempt)
+			if delay < 0 || delay > DefaultRetryMaxDelay {
+				t.Fatalf("expect delay of %T in [0, %s], got %s", delayer, DefaultRetryMaxDelay, delay)
+			}
+		}
+	}
+}
+
+func TestWithRetryDelayer(t *testing.T) {
+	retryer := BasicRetryer{}.WithOptions(WithRetryDelayer(retry.ConstantDelayer{Duration: time.Second}))
+	if delay := retryer.Delay(2); delay != time.Second {
+		t.Fatalf("expect delay is 1s, got %s", delay)
+	}
+}
+
+func TestRetryBuilderSleep(t *testing.T) {
+	var delays []time.Duration
+	build := RetryBuilder{
+		Retryer: BasicRetryer{}.WithOptions(WithRetryDelayer(LinearDelayer{Initial: time.Second})),
+		Sleep: func(ctx context.Context, d time.Duration) error {
+			delays = append(delays, d)
+			return nil
+		},
+	}.Builder(
+		func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+			err = &httpc.ResponseError{
+				Response: &http.Response{StatusCode: http.StatusServiceUnavailable},
+				Err:      errors.New("unavailable"),
+			}
+			return
+		},
+	)
+
+	req := &httpc.Request{
+		Request: &http.Request{URL: &url.URL{}, Header: http.Header{}},
+	}
+	_, _, err := build(context.Background(), req)
+	var maxErr *MaxAttemptsExceededError
+	if !errors.As(err, &maxErr) {
+		t.Fatalf("expect MaxAttemptsExceededError, got %v", err)
+	}
+	expectDelays := []time.Duration{2 * time.Second, 3 * time.Second}
+	if len(delays) != len(expectDelays) || delays[0] != expectDelays[0] || delays[1] != expectDelays[1] {
+		t.Fatalf("expect delays are %v, got %v", expectDelays, delays)
+	}
+}
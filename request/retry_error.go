@@ -0,0 +1,121 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The sentinel errors of RetryBuilder, they can be checked by errors.Is.
+var (
+	ErrMaxAttemptsExceeded = errors.New("max attempts exceeded")
+	ErrNonRetryable        = errors.New("non-retryable")
+	ErrRetryCanceled       = errors.New("retry canceled")
+	ErrBodyRewind          = errors.New("body rewind failed")
+	ErrQuotaExceeded       = errors.New("retry quota exceeded")
+)
+
+// RetryError is implemented by all errors returned by RetryBuilder.
+type RetryError interface {
+	error
+
+	// RetryAttempts returns the number of attempts made.
+	RetryAttempts() int
+
+	// Retryable reports whether the call may succeed if it is made again later,
+	// e.g. a queue consumer can decide whether to redeliver the message.
+	Retryable() bool
+}
+
+var (
+	_ RetryError = (*MaxAttemptsExceededError)(nil)
+	_ RetryError = (*NonRetryableError)(nil)
+	_ RetryError = (*RetryCanceledError)(nil)
+	_ RetryError = (*BodyRewindError)(nil)
+	_ RetryError = (*QuotaExceededError)(nil)
+)
+
+func retryErrorString(attempts int, message string, err error) string {
+	return fmt.Sprintf("request retry finalizer, attempts %d, %s, %v", attempts, message, err)
+}
+
+// MaxAttemptsExceededError is returned when the last attempt fails with a retryable error,
+// a non-retryable error is returned as NonRetryableError even on the last attempt.
+type MaxAttemptsExceededError struct {
+	Attempts    int
+	MaxAttempts int
+	Err         error
+}
+
+func (e *MaxAttemptsExceededError) Error() string {
+	return retryErrorString(e.Attempts, fmt.Sprintf("max attempts %d exhausted", e.MaxAttempts), e.Err)
+}
+
+func (e *MaxAttemptsExceededError) Unwrap() error        { return e.Err }
+func (e *MaxAttemptsExceededError) Is(target error) bool { return target == ErrMaxAttemptsExceeded }
+func (e *MaxAttemptsExceededError) RetryAttempts() int   { return e.Attempts }
+func (e *MaxAttemptsExceededError) Retryable() bool      { return true }
+
+// NonRetryableError is returned when the Retryer does not retry the error.
+type NonRetryableError struct {
+	Attempts int
+	// Check is the result of Retryer.Check.
+	Check Retryable
+	Err   error
+}
+
+func (e *NonRetryableError) Error() string {
+	return retryErrorString(e.Attempts, fmt.Sprintf("retryable %s", e.Check), e.Err)
+}
+
+func (e *NonRetryableError) Unwrap() error        { return e.Err }
+func (e *NonRetryableError) Is(target error) bool { return target == ErrNonRetryable }
+func (e *NonRetryableError) RetryAttempts() int   { return e.Attempts }
+func (e *NonRetryableError) Retryable() bool      { return false }
+
+// RetryCanceledError is returned when the context is done
+// while attempting, sleeping or waiting for the rate limiter.
+type RetryCanceledError struct {
+	Attempts int
+	// While is what RetryBuilder was doing: "attempt", "sleep" or "rate limit wait".
+	While string
+	Err   error
+}
+
+func (e *RetryCanceledError) Error() string {
+	return retryErrorString(e.Attempts, e.While+" canceled", e.Err)
+}
+
+func (e *RetryCanceledError) Unwrap() error        { return e.Err }
+func (e *RetryCanceledError) Is(target error) bool { return target == ErrRetryCanceled }
+func (e *RetryCanceledError) RetryAttempts() int   { return e.Attempts }
+func (e *RetryCanceledError) Retryable() bool      { return true }
+
+// BodyRewindError is returned when the request body cannot be rewound.
+type BodyRewindError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *BodyRewindError) Error() string {
+	return retryErrorString(e.Attempts, "body rewind failed", e.Err)
+}
+
+func (e *BodyRewindError) Unwrap() error        { return e.Err }
+func (e *BodyRewindError) Is(target error) bool { return target == ErrBodyRewind }
+func (e *BodyRewindError) RetryAttempts() int   { return e.Attempts }
+func (e *BodyRewindError) Retryable() bool      { return false }
+
+// QuotaExceededError is returned by RetryBuilder when RetryTokenBucket runs out of tokens.
+type QuotaExceededError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *QuotaExceededError) Error() string {
+	return retryErrorString(e.Attempts, "retry quota exceeded", e.Err)
+}
+
+func (e *QuotaExceededError) Unwrap() error        { return e.Err }
+func (e *QuotaExceededError) Is(target error) bool { return target == ErrQuotaExceeded }
+func (e *QuotaExceededError) RetryAttempts() int   { return e.Attempts }
+func (e *QuotaExceededError) Retryable() bool      { return true }
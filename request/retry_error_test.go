@@ -0,0 +1,118 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-camp/httpc"
+)
+
+func TestRetryBuilderErrors(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		MaxAttempts   int
+		DelayDuration time.Duration
+		Retryable     Retryable
+		Body          io.Reader
+
+		ExpectIs        error
+		ExpectAttempts  int
+		ExpectRetryable bool
+	}{
+		{
+			Name:            "max attempts exceeded",
+			MaxAttempts:     2,
+			Retryable:       RetryableYes,
+			ExpectIs:        ErrMaxAttemptsExceeded,
+			ExpectAttempts:  2,
+			ExpectRetryable: true,
+		},
+		{
+			Name:            "non-retryable",
+			MaxAttempts:     2,
+			Retryable:       RetryableNo,
+			ExpectIs:        ErrNonRetryable,
+			ExpectAttempts:  1,
+			ExpectRetryable: false,
+		},
+		{
+			Name:            "non-retryable on last attempt",
+			MaxAttempts:     1,
+			Retryable:       RetryableNo,
+			ExpectIs:        ErrNonRetryable,
+			ExpectAttempts:  1,
+			ExpectRetryable: false,
+		},
+		{
+			Name:            "sleep canceled",
+			MaxAttempts:     2,
+			DelayDuration:   time.Second,
+			Retryable:       RetryableYes,
+			ExpectIs:        ErrRetryCanceled,
+			ExpectAttempts:  1,
+			ExpectRetryable: true,
+		},
+		{
+			Name:            "body rewind failed",
+			MaxAttempts:     2,
+			Retryable:       RetryableYes,
+			Body:            bytes.NewBufferString("1"),
+			ExpectIs:        ErrBodyRewind,
+			ExpectAttempts:  1,
+			ExpectRetryable: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			attemptErr := errors.New("attempt err")
+			build := RetryBuilder{
+				Retryer: &testRetryer{
+					M: tc.MaxAttempts,
+					D: func(attempt int) time.Duration { return tc.DelayDuration },
+					C: func(error) Retryable { return tc.Retryable },
+				},
+			}.Builder(
+				func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+					return output, md, attemptErr
+				},
+			)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			req := &httpc.Request{
+				Request: &http.Request{URL: &url.URL{}, Header: http.Header{}},
+				Body:    tc.Body,
+			}
+			_, _, err := build(ctx, req)
+
+			if !errors.Is(err, tc.ExpectIs) {
+				t.Fatalf("expect err is %v, got %v", tc.ExpectIs, err)
+			}
+			var retryErr RetryError
+			if !errors.As(err, &retryErr) {
+				t.Fatalf("expect err is RetryError, got %T", err)
+			}
+			if retryErr.RetryAttempts() != tc.ExpectAttempts {
+				t.Fatalf("expect %d attempts, got %d", tc.ExpectAttempts, retryErr.RetryAttempts())
+			}
+			if retryErr.Retryable() != tc.ExpectRetryable {
+				t.Fatalf("expect retryable is %v, got %v", tc.ExpectRetryable, retryErr.Retryable())
+			}
+		})
+	}
+
+	maxErr := &MaxAttemptsExceededError{Attempts: 3, MaxAttempts: 3, Err: errors.New("err")}
+	wrapped := fmt.Errorf("consume: %w", maxErr)
+	var target *MaxAttemptsExceededError
+	if !errors.As(wrapped, &target) || target.Attempts != 3 {
+		t.Fatalf("expect errors.As finds MaxAttemptsExceededError, got %v", wrapped)
+	}
+}
@@ -0,0 +1,61 @@
+package request
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-camp/httpc"
+	"github.com/go-camp/httpc/response"
+)
+
+type mdRetryKey struct{}
+
+// AttemptMetadata records an attempt of RetryBuilder.
+type AttemptMetadata struct {
+	// Attempt is the attempt number, starting from 1.
+	Attempt int
+	Start   time.Time
+	Latency time.Duration
+	// StatusCode is the response status code, 0 if there is no response.
+	StatusCode int
+	// RequestID is the server request id set by RequestIDDeserializer.
+	RequestID string
+	Err       error
+	// Delay is the delay before the next attempt.
+	Delay time.Duration
+}
+
+// RetryMetadata records the attempts of RetryBuilder.
+type RetryMetadata struct {
+	Attempts []AttemptMetadata
+}
+
+// GetAttempts gets the attempts recorded by RetryBuilder from metadata.
+func GetAttempts(md httpc.Metadata) (rm RetryMetadata) {
+	v := md.Get(mdRetryKey{})
+	rm, _ = v.(RetryMetadata)
+	return rm
+}
+
+func newAttemptMetadata(attempt int, start time.Time, md httpc.Metadata, err error) AttemptMetadata {
+	am := AttemptMetadata{
+		Attempt:   attempt,
+		Start:     start,
+		Latency:   time.Since(start),
+		RequestID: response.GetRequestID(md),
+		Err:       err,
+	}
+
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) {
+		am.StatusCode = statusErr.HTTPStatusCode()
+	} else if resp := response.GetResponse(md); resp != nil {
+		am.StatusCode = resp.StatusCode
+	}
+
+	var requestIDErr interface{ HTTPRequestID() string }
+	if am.RequestID == "" && errors.As(err, &requestIDErr) {
+		am.RequestID = requestIDErr.HTTPRequestID()
+	}
+	return am
+}
@@ -0,0 +1,86 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-camp/httpc"
+)
+
+func TestRetryBuilderAttempts(t *testing.T) {
+	testCases := []struct {
+		Name        string
+		MaxAttempts int
+
+		ExpectAttempts int
+		ExpectError    bool
+	}{
+		{
+			Name:           "success",
+			MaxAttempts:    3,
+			ExpectAttempts: 2,
+		},
+		{
+			Name:           "failure",
+			MaxAttempts:    1,
+			ExpectAttempts: 1,
+			ExpectError:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var buildCount int
+			build := RetryBuilder{
+				Retryer: &testRetryer{
+					M: tc.MaxAttempts,
+					D: func(attempt int) time.Duration { return time.Millisecond },
+					C: func(error) Retryable { return RetryableYes },
+				},
+			}.Builder(
+				func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+					buildCount++
+					if buildCount == 1 {
+						err = &httpc.ResponseError{
+							Response:  &http.Response{StatusCode: http.StatusServiceUnavailable},
+							RequestID: "request-1",
+							Err:       errors.New("unavailable"),
+						}
+					}
+					return
+				},
+			)
+
+			req := &httpc.Request{
+				Request: &http.Request{URL: &url.URL{}, Header: http.Header{}},
+			}
+			_, md, err := build(context.Background(), req)
+			if (err != nil) != tc.ExpectError {
+				t.Fatalf("expect err %v, got %v", tc.ExpectError, err)
+			}
+
+			attempts := GetAttempts(md).Attempts
+			if len(attempts) != tc.ExpectAttempts {
+				t.Fatalf("expect %d attempts, got %d", tc.ExpectAttempts, len(attempts))
+			}
+			first := attempts[0]
+			if first.Attempt != 1 || first.StatusCode != http.StatusServiceUnavailable ||
+				first.RequestID != "request-1" || first.Err == nil || first.Start.IsZero() {
+				t.Fatalf("unexpected first attempt %+v", first)
+			}
+			if tc.ExpectAttempts > 1 {
+				if first.Delay != time.Millisecond {
+					t.Fatalf("expect first attempt delay is 1ms, got %s", first.Delay)
+				}
+				last := attempts[len(attempts)-1]
+				if last.Attempt != 2 || last.Err != nil || last.Delay != 0 {
+					t.Fatalf("unexpected last attempt %+v", last)
+				}
+			}
+		})
+	}
+}
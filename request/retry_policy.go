@@ -0,0 +1,227 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-camp/httpc"
+)
+
+// RequestRetryer is an optional interface of Retryer.
+// RetryBuilder uses CheckRequest instead of Check and MaxAttempts if the Retryer implements it.
+type RequestRetryer interface {
+	// CheckRequest checks if err of req can be retried,
+	// and returns the maximum number of attempts for err.
+	CheckRequest(req *httpc.Request, err error) (retryable Retryable, maxAttempts int)
+}
+
+// RetryRule matches the errors of requests.
+//
+// A rule matches if the request method matches Methods,
+// and the error matches any of StatusCodes, ErrorCodes, Faults and ErrorTypes.
+// A rule without any error condition matches all errors.
+type RetryRule struct {
+	// Methods are the HTTP methods the rule applies to.
+	// If Methods is empty, the rule applies to the methods of RetryPolicy.
+	Methods []string
+	// StatusCodes match errors with an HTTPStatusCode method, such as ResponseError.
+	StatusCodes []int
+	// ErrorCodes match errors with an ErrorCode method, such as APIError.
+	ErrorCodes []string
+	// Faults match errors with an ErrorFault method, such as APIError.
+	Faults []httpc.ErrorFault
+	// ErrorTypes match errors by errors.As with the type of each element,
+	// for example []error{(*net.OpError)(nil)}.
+	ErrorTypes []error
+
+	// Retryable is the result of the rule.
+	// If Retryable is RetryableUnknown, then RetryableYes is used.
+	Retryable Retryable
+	// MaxAttempts overrides the maximum number of attempts of the Retryer.
+	// If MaxAttempts is 0, the maximum number of attempts is not overridden.
+	MaxAttempts int
+}
+
+func (r RetryRule) retryable() Retryable {
+	if r.Retryable == RetryableUnknown {
+		return RetryableYes
+	}
+	return r.Retryable
+}
+
+func (r RetryRule) hasMethod(method string) bool {
+	for _, m := range r.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r RetryRule) matchError(err error) bool {
+	if len(r.StatusCodes) == 0 && len(r.ErrorCodes) == 0 && len(r.Faults) == 0 && len(r.ErrorTypes) == 0 {
+		return true
+	}
+
+	var statusErr interface{ HTTPStatusCode() int }
+	if len(r.StatusCodes) > 0 && errors.As(err, &statusErr) {
+		code := statusErr.HTTPStatusCode()
+		for _, c := range r.StatusCodes {
+			if c == code {
+				return true
+			}
+		}
+	}
+	var codeErr interface{ ErrorCode() string }
+	if len(r.ErrorCodes) > 0 && errors.As(err, &codeErr) {
+		code := codeErr.ErrorCode()
+		for _, c := range r.ErrorCodes {
+			if c == code {
+				return true
+			}
+		}
+	}
+	var faultErr interface{ ErrorFault() httpc.ErrorFault }
+	if len(r.Faults) > 0 && errors.As(err, &faultErr) {
+		fault := faultErr.ErrorFault()
+		for _, f := range r.Faults {
+			if f == fault {
+				return true
+			}
+		}
+	}
+	for _, t := range r.ErrorTypes {
+		if t == nil {
+			continue
+		}
+		target := reflect.New(reflect.TypeOf(t))
+		if errors.As(err, target.Interface()) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryPolicy is a Retryer which checks errors by rules.
+//
+// The rules are checked in order and the first matched rule decides.
+// If no rule matches, a request with a method of Methods is checked by Retryer,
+// and a request with another method is not retried,
+// unless the request has an idempotency key set by IdempotencyKeyBuilder.
+//
+// To limit the sending rate, use AdaptiveRetryer as the Retryer.
+type RetryPolicy struct {
+	// Retryer provides the delay, the maximum number of attempts and the check if no rule matches.
+	// If Retryer is nil, then DefaultRetryer is used.
+	Retryer Retryer
+	// Methods are the HTTP methods which can be retried.
+	// If Methods is nil, only idempotent methods can be retried.
+	Methods []string
+	Rules   []RetryRule
+	// A request with the IdempotencyKeyHeader header can be retried regardless of its method.
+	// default: Idempotency-Key
+	IdempotencyKeyHeader string
+}
+
+var (
+	_ RequestRetryer     = RetryPolicy{}
+	_ AttemptRateLimiter = RetryPolicy{}
+)
+
+func (p RetryPolicy) retryer() Retryer {
+	if p.Retryer == nil {
+		return DefaultRetryer
+	}
+	return p.Retryer
+}
+
+func (p RetryPolicy) idempotencyKeyHeader() string {
+	if p.IdempotencyKeyHeader == "" {
+		return headerIdempotencyKey
+	}
+	return p.IdempotencyKeyHeader
+}
+
+func (p RetryPolicy) allowRequest(req *httpc.Request, method string) bool {
+	if req != nil && req.Request != nil && req.Header.Get(p.idempotencyKeyHeader()) != "" {
+		return true
+	}
+	return p.allowMethod(method)
+}
+
+func (p RetryPolicy) allowMethod(method string) bool {
+	if p.Methods == nil {
+		return isIdempotentMethod(method)
+	}
+	for _, m := range p.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p RetryPolicy) MaxAttempts() int {
+	return p.retryer().MaxAttempts()
+}
+
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	return p.retryer().Delay(attempt)
+}
+
+// Check checks err by the rules without Methods,
+// it is used when the request is unknown.
+func (p RetryPolicy) Check(err error) Retryable {
+	retryable, _ := p.check("", true, err)
+	return retryable
+}
+
+func (p RetryPolicy) CheckRequest(req *httpc.Request, err error) (retryable Retryable, maxAttempts int) {
+	method := http.MethodGet
+	if req != nil && req.Request != nil && req.Method != "" {
+		method = req.Method
+	}
+	return p.check(method, p.allowRequest(req, method), err)
+}
+
+func (p RetryPolicy) check(method string, allowed bool, err error) (retryable Retryable, maxAttempts int) {
+	retryer := p.retryer()
+	maxAttempts = retryer.MaxAttempts()
+	for _, rule := range p.Rules {
+		if len(rule.Methods) == 0 {
+			if !allowed {
+				continue
+			}
+		} else if !rule.hasMethod(method) {
+			continue
+		}
+		if !rule.matchError(err) {
+			continue
+		}
+		if rule.MaxAttempts != 0 {
+			maxAttempts = rule.MaxAttempts
+		}
+		return rule.retryable(), maxAttempts
+	}
+	if !allowed {
+		return RetryableNo, maxAttempts
+	}
+	return retryer.Check(err), maxAttempts
+}
+
+func (p RetryPolicy) WaitAttempt(ctx context.Context) error {
+	if limiter, ok := p.retryer().(AttemptRateLimiter); ok {
+		return limiter.WaitAttempt(ctx)
+	}
+	return nil
+}
+
+func (p RetryPolicy) RecordAttempt(err error) {
+	if limiter, ok := p.retryer().(AttemptRateLimiter); ok {
+		limiter.RecordAttempt(err)
+	}
+}
@@ -0,0 +1,214 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/go-camp/httpc"
+)
+
+func TestRetryPolicyCheckRequest(t *testing.T) {
+	policy := RetryPolicy{
+		Retryer: BasicRetryer{Options: BasicRetryerOptions{MaxAttempts: 3}},
+		Rules: []RetryRule{
+			{
+				ErrorCodes:  []string{"Throttling"},
+				MaxAttempts: 5,
+			},
+			{
+				Faults:    []httpc.ErrorFault{httpc.ErrorFaultClient},
+				Retryable: RetryableNo,
+			},
+			{
+				Methods:     []string{http.MethodPost},
+				StatusCodes: []int{http.StatusServiceUnavailable},
+				MaxAttempts: 2,
+			},
+			{
+				ErrorTypes: []error{(*net.OpError)(nil)},
+			},
+		},
+	}
+	statusErr := func(code int) error {
+		return &httpc.ResponseError{
+			Response: &http.Response{StatusCode: code},
+			Err:      errors.New("failed"),
+		}
+	}
+
+	testCases := []struct {
+		Name           string
+		Method         string
+		IdempotencyKey string
+		Err            error
+
+		ExpectRetryable   Retryable
+		ExpectMaxAttempts int
+	}{
+		{
+			Name:              "error_code",
+			Method:            http.MethodGet,
+			Err:               &httpc.GenericAPIError{Code: "Throttling"},
+			ExpectRetryable:   RetryableYes,
+			ExpectMaxAttempts: 5,
+		},
+		{
+			Name:              "fault",
+			Method:            http.MethodGet,
+			Err:               &httpc.GenericAPIError{Code: "Invalid", Fault: httpc.ErrorFaultClient},
+			ExpectRetryable:   RetryableNo,
+			ExpectMaxAttempts: 3,
+		},
+		{
+			Name:              "fallback",
+			Method:            http.MethodGet,
+			Err:               statusErr(http.StatusServiceUnavailable),
+			ExpectRetryable:   RetryableYes,
+			ExpectMaxAttempts: 3,
+		},
+		{
+			Name:              "error_type",
+			Method:            http.MethodPut,
+			Err:               &url.Error{Op: "Put", Err: &net.OpError{Op: "read", Err: errors.New("eof")}},
+			ExpectRetryable:   RetryableYes,
+			ExpectMaxAttempts: 3,
+		},
+		{
+			Name:              "non_idempotent_method",
+			Method:            http.MethodPost,
+			Err:               &httpc.GenericAPIError{Code: "Throttling"},
+			ExpectRetryable:   RetryableNo,
+			ExpectMaxAttempts: 3,
+		},
+		{
+			Name:              "idempotency_key",
+			Method:            http.MethodPost,
+			IdempotencyKey:    "key",
+			Err:               &httpc.GenericAPIError{Code: "Throttling"},
+			ExpectRetryable:   RetryableYes,
+			ExpectMaxAttempts: 5,
+		},
+		{
+			Name:              "method_rule",
+			Method:            http.MethodPost,
+			Err:               statusErr(http.StatusServiceUnavailable),
+			ExpectRetryable:   RetryableYes,
+			ExpectMaxAttempts: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := &httpc.Request{Request: &http.Request{Method: tc.Method, Header: http.Header{}}}
+			if tc.IdempotencyKey != "" {
+				req.Header.Set("Idempotency-Key", tc.IdempotencyKey)
+			}
+			retryable, maxAttempts := policy.CheckRequest(req, tc.Err)
+			if retryable != tc.ExpectRetryable {
+				t.Fatalf("expect retryable is %s, got %s", tc.ExpectRetryable, retryable)
+			}
+			if maxAttempts != tc.ExpectMaxAttempts {
+				t.Fatalf("expect max attempts is %d, got %d", tc.ExpectMaxAttempts, maxAttempts)
+			}
+		})
+	}
+}
+
+func TestRetryBuilderRetryPolicy(t *testing.T) {
+	testCases := []struct {
+		Name        string
+		Method      string
+		MaxAttempts int
+		Adaptive    bool
+
+		ExpectAttempts int
+		ExpectError    interface{}
+	}{
+		{
+			Name:           "get",
+			Method:         http.MethodGet,
+			MaxAttempts:    4,
+			ExpectAttempts: 4,
+			ExpectError:    &MaxAttemptsExceededError{},
+		},
+		{
+			Name:           "post",
+			Method:         http.MethodPost,
+			MaxAttempts:    4,
+			ExpectAttempts: 1,
+			ExpectError:    &NonRetryableError{},
+		},
+		{
+			Name:           "post on last attempt",
+			Method:         http.MethodPost,
+			MaxAttempts:    1,
+			ExpectAttempts: 1,
+			ExpectError:    &NonRetryableError{},
+		},
+		{
+			Name:           "adaptive get",
+			Method:         http.MethodGet,
+			MaxAttempts:    4,
+			Adaptive:       true,
+			ExpectAttempts: 4,
+			ExpectError:    &MaxAttemptsExceededError{},
+		},
+		{
+			Name:           "adaptive post",
+			Method:         http.MethodPost,
+			MaxAttempts:    4,
+			Adaptive:       true,
+			ExpectAttempts: 1,
+			ExpectError:    &NonRetryableError{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var attempts int
+			var retryer Retryer = RetryPolicy{
+				Retryer: BasicRetryer{Options: BasicRetryerOptions{Delayer: NopRetryDelayer}},
+				Rules: []RetryRule{
+					{StatusCodes: []int{http.StatusServiceUnavailable}, MaxAttempts: tc.MaxAttempts},
+				},
+			}
+			if tc.Adaptive {
+				retryer = AdaptiveRetryer{Retryer: retryer, RateLimiter: &ClientRateLimiter{}}
+			}
+			build := RetryBuilder{Retryer: retryer}.Builder(
+				func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+					attempts++
+					err = &httpc.ResponseError{
+						Response: &http.Response{StatusCode: http.StatusServiceUnavailable},
+						Err:      errors.New("unavailable"),
+					}
+					return
+				},
+			)
+
+			req := &httpc.Request{
+				Request: &http.Request{Method: tc.Method, URL: &url.URL{}, Header: http.Header{}},
+			}
+			_, _, err := build(context.Background(), req)
+			if attempts != tc.ExpectAttempts {
+				t.Fatalf("expect attempts is %d, got %d", tc.ExpectAttempts, attempts)
+			}
+			switch tc.ExpectError.(type) {
+			case *MaxAttemptsExceededError:
+				var target *MaxAttemptsExceededError
+				if !errors.As(err, &target) || target.MaxAttempts != tc.ExpectAttempts {
+					t.Fatalf("expect MaxAttemptsExceededError, got %v", err)
+				}
+			case *NonRetryableError:
+				var target *NonRetryableError
+				if !errors.As(err, &target) || target.Check != RetryableNo {
+					t.Fatalf("expect NonRetryableError, got %v", err)
+				}
+			}
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/go-camp/httpc"
+	"github.com/go-camp/httpc/response"
 )
 
 type testRetryer struct {
@@ -69,7 +70,7 @@ func TestRetryBuilder(t *testing.T) {
 			Retryable:          RetryableYes,
 			Body:               bytes.NewReader([]byte("1")),
 
-			ExpectError: "request retry finalizer, attempts 2, sleep canceled, context deadline exceeded",
+			ExpectError: "request retry finalizer, attempts 1, sleep canceled, context deadline exceeded",
 		},
 		{
 			Name:               "ctx canceled",
@@ -79,7 +80,7 @@ func TestRetryBuilder(t *testing.T) {
 			Retryable:          RetryableYes,
 			Body:               bytes.NewBuffer([]byte("1")),
 
-			ExpectError: "request retry finalizer, attempts 2, body rewind failed, request body cannot be rewinded",
+			ExpectError: "request retry finalizer, attempts 1, body rewind failed, request body cannot be rewinded",
 		},
 		{
 			Name:               "final success",
@@ -201,6 +202,18 @@ func TestBasicRetryer(t *testing.T) {
 				Err: errors.New("temp unavailable"),
 			},
 
+			ExpectRetryable: RetryableYes,
+		},
+		{
+			Name: "too many requests",
+			Err: &httpc.ResponseError{
+				Response: &http.Response{
+					Header:     http.Header{},
+					StatusCode: http.StatusTooManyRequests,
+				},
+				Err: errors.New("throttled"),
+			},
+
 			ExpectRetryable: RetryableYes,
 		},
 	}
@@ -213,3 +226,235 @@ func TestBasicRetryer(t *testing.T) {
 		})
 	}
 }
+
+func TestRetryBuilderRetryAfter(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	testCases := []struct {
+		Name          string
+		MaxRetryAfter time.Duration
+		Date          time.Time
+		RetryAfter    string
+
+		ExpectDelay time.Duration
+		ExpectOK    bool
+	}{
+		{
+			Name: "no header",
+		},
+		{
+			Name:        "seconds",
+			RetryAfter:  "3",
+			ExpectDelay: 3 * time.Second,
+			ExpectOK:    true,
+		},
+		{
+			Name:          "seconds capped",
+			MaxRetryAfter: 2 * time.Second,
+			RetryAfter:    "3",
+			ExpectDelay:   2 * time.Second,
+			ExpectOK:      true,
+		},
+		{
+			Name:          "ignored",
+			MaxRetryAfter: -1,
+			RetryAfter:    "3",
+		},
+		{
+			Name:        "http date",
+			Date:        now,
+			RetryAfter:  now.Add(5 * time.Second).Format(http.TimeFormat),
+			ExpectDelay: 5 * time.Second,
+			ExpectOK:    true,
+		},
+		{
+			Name:        "http date in the past",
+			Date:        now,
+			RetryAfter:  now.Add(-5 * time.Second).Format(http.TimeFormat),
+			ExpectDelay: 0,
+			ExpectOK:    true,
+		},
+		{
+			Name:       "invalid",
+			RetryAfter: "soon",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			header := http.Header{}
+			if !tc.Date.IsZero() {
+				header.Set("Date", tc.Date.Format(http.TimeFormat))
+			}
+			if tc.RetryAfter != "" {
+				header.Set("Retry-After", tc.RetryAfter)
+			}
+			resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: header}
+			deserialize := response.DateDeserializer{}.Deserializer(
+				func(req *http.Request) (output httpc.DeserializeOutput, md httpc.Metadata, err error) {
+					output.Response = resp
+					return
+				},
+			)
+			_, md, _ := deserialize(&http.Request{})
+			err := &httpc.ResponseError{Response: resp, Err: errors.New("throttled")}
+
+			delay, ok := RetryBuilder{MaxRetryAfter: tc.MaxRetryAfter}.retryAfter(err, md)
+			if tc.ExpectOK != ok {
+				t.Fatalf("expect ok is %v, got %v", tc.ExpectOK, ok)
+			}
+			if tc.ExpectDelay != delay {
+				t.Fatalf("expect delay is %s, got %s", tc.ExpectDelay, delay)
+			}
+		})
+	}
+}
+
+func TestRetryBuilderAttemptTimeout(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		ParentTimeout time.Duration
+
+		ExpectAttempts int
+		ExpectError    string
+	}{
+		{
+			Name:           "attempt timeout retried",
+			ParentTimeout:  time.Second,
+			ExpectAttempts: 2,
+		},
+		{
+			Name:           "parent deadline stops retry",
+			ParentTimeout:  30 * time.Millisecond,
+			ExpectAttempts: 1,
+			ExpectError:    "request retry finalizer, attempts 1, attempt canceled, context deadline exceeded",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var attempts int
+			build := RetryBuilder{
+				Retryer: BasicRetryer{
+					Options: BasicRetryerOptions{Delayer: NopRetryDelayer},
+				},
+				AttemptTimeout: 50 * time.Millisecond,
+			}.Builder(
+				func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+					attempts++
+					if attempts == 1 {
+						<-req.Context().Done()
+						return output, md, req.Context().Err()
+					}
+					return
+				},
+			)
+
+			ctx, cancel := context.WithTimeout(context.Background(), tc.ParentTimeout)
+			defer cancel()
+			req, _ := httpc.NewRequest(ctx, http.MethodGet, "http://example.com", nil)
+			_, _, err := build(ctx, req)
+			if attempts != tc.ExpectAttempts {
+				t.Fatalf("expect %d attempts, got %d", tc.ExpectAttempts, attempts)
+			}
+			if tc.ExpectError == "" {
+				if err != nil {
+					t.Fatalf("expect no err, got %v", err)
+				}
+				return
+			}
+			if err == nil || tc.ExpectError != err.Error() {
+				t.Fatalf("expect err is %s, got %v", tc.ExpectError, err)
+			}
+		})
+	}
+}
+
+func TestRetryableConnectionErrorCheckerAttemptTimeout(t *testing.T) {
+	err := &AttemptTimeoutError{Duration: time.Second, Err: context.DeadlineExceeded}
+	if retryable := (RetryableConnectionErrorChecker{}).Check(err); retryable != RetryableYes {
+		t.Fatalf("expect retryable is %s, got %s", RetryableYes, retryable)
+	}
+}
+
+func TestWithRetryer(t *testing.T) {
+	var calls int
+	h := httpc.Handler{
+		Serializer: func(serialize httpc.SerializeFunc) httpc.SerializeFunc {
+			return func(ctx context.Context, input httpc.SerializeInput) (output interface{}, md httpc.Metadata, err error) {
+				input.Request, err = httpc.NewRequest(ctx, http.MethodGet, "http://example.com/", nil)
+				if err != nil {
+					return
+				}
+				return serialize(ctx, input)
+			}
+		},
+		Builder: RetryBuilder{
+			Retryer: &testRetryer{
+				M: 3,
+				D: func(attempt int) time.Duration { return 0 },
+				C: func(err error) Retryable { return RetryableYes },
+			},
+		}.Builder,
+		Finalizer: func(finalize httpc.FinalizeFunc) httpc.FinalizeFunc {
+			return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
+				if ctx.Value(retryerKey{}) != nil || req.Context().Value(retryerKey{}) != nil {
+					t.Fatalf("expect retryer is not passed to the steps after RetryBuilder")
+				}
+				return finalize(ctx, req)
+			}
+		},
+		Do: func(req *http.Request) (*http.Response, error) {
+			calls++
+			return nil, io.ErrUnexpectedEOF
+		},
+	}
+
+	retryer := &testRetryer{
+		M: 2,
+		D: func(attempt int) time.Duration { return 0 },
+		C: func(err error) Retryable { return RetryableYes },
+	}
+	_, _, err := h.Handle(context.Background(), nil, WithRetryer(retryer), WithRetryer(retryer))
+	if !errors.Is(err, ErrMaxAttemptsExceeded) {
+		t.Fatalf("expect max attempts exceeded, got %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expect calls is 2, got %d", calls)
+	}
+
+	calls = 0
+	_, _, _ = h.Handle(context.Background(), nil)
+	if calls != 3 {
+		t.Fatalf("expect calls is 3 without option, got %d", calls)
+	}
+}
+
+func TestRetryBuilderAttemptTimeoutResponseBody(t *testing.T) {
+	var reqCtx context.Context
+	build := RetryBuilder{AttemptTimeout: time.Second}.Builder(
+		func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+			reqCtx = req.Context()
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader([]byte("body"))),
+			}, md, nil
+		},
+	)
+
+	req, _ := httpc.NewRequest(context.Background(), http.MethodGet, "http://example.com", nil)
+	output, _, err := build(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expect no err, got %v", err)
+	}
+	if err := reqCtx.Err(); err != nil {
+		t.Fatalf("expect the attempt is not canceled before the body is closed, got %v", err)
+	}
+	resp := output.(*http.Response)
+	if body, _ := io.ReadAll(resp.Body); string(body) != "body" {
+		t.Fatalf("expect body is body, got %q", body)
+	}
+	resp.Body.Close()
+	if reqCtx.Err() == nil {
+		t.Fatalf("expect the attempt is canceled after the body is closed")
+	}
+}
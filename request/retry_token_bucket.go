@@ -0,0 +1,113 @@
+package request
+
+import (
+	"errors"
+	"sync"
+)
+
+const (
+	DefaultRetryTokenBucketCapacity = 500
+	DefaultRetryCost                = 5
+	DefaultRetryTimeoutCost         = 10
+	DefaultRetrySuccessIncrement    = 1
+)
+
+// RetryTokenBucket is a client-side retry quota which can be shared by RetryBuilders.
+//
+// Every retry costs tokens, a retry after a timeout error costs more.
+// A successful attempt refunds the cost of its retry,
+// or refills SuccessIncrement tokens if it is the first attempt.
+// When the bucket does not have enough tokens, RetryBuilder stops retrying
+// and returns QuotaExceededError.
+type RetryTokenBucket struct {
+	// If Capacity is 0, then DefaultRetryTokenBucketCapacity is used.
+	Capacity int
+	// If RetryCost is 0, then DefaultRetryCost is used.
+	RetryCost int
+	// If TimeoutCost is 0, then DefaultRetryTimeoutCost is used.
+	TimeoutCost int
+	// If SuccessIncrement is 0, then DefaultRetrySuccessIncrement is used.
+	SuccessIncrement int
+
+	mux         sync.Mutex
+	initialized bool
+	tokens      int
+}
+
+func (b *RetryTokenBucket) capacity() int {
+	if b.Capacity == 0 {
+		return DefaultRetryTokenBucketCapacity
+	}
+	return b.Capacity
+}
+
+func (b *RetryTokenBucket) retryCost() int {
+	if b.RetryCost == 0 {
+		return DefaultRetryCost
+	}
+	return b.RetryCost
+}
+
+func (b *RetryTokenBucket) timeoutCost() int {
+	if b.TimeoutCost == 0 {
+		return DefaultRetryTimeoutCost
+	}
+	return b.TimeoutCost
+}
+
+func (b *RetryTokenBucket) successIncrement() int {
+	if b.SuccessIncrement == 0 {
+		return DefaultRetrySuccessIncrement
+	}
+	return b.SuccessIncrement
+}
+
+func (b *RetryTokenBucket) init() {
+	if !b.initialized {
+		b.tokens = b.capacity()
+		b.initialized = true
+	}
+}
+
+// Available returns the number of available tokens.
+func (b *RetryTokenBucket) Available() int {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.init()
+	return b.tokens
+}
+
+// Acquire takes the cost of retrying after err from the bucket.
+// ok is false if the bucket does not have enough tokens.
+func (b *RetryTokenBucket) Acquire(err error) (cost int, ok bool) {
+	cost = b.retryCost()
+	var timeoutErr interface{ Timeout() bool }
+	if errors.As(err, &timeoutErr) && timeoutErr.Timeout() {
+		cost = b.timeoutCost()
+	}
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.init()
+	if b.tokens < cost {
+		return 0, false
+	}
+	b.tokens -= cost
+	return cost, true
+}
+
+// Release refills the bucket after a successful attempt.
+// cost is the value returned by Acquire for the retry, or 0 for the first attempt.
+func (b *RetryTokenBucket) Release(cost int) {
+	if cost <= 0 {
+		cost = b.successIncrement()
+	}
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.init()
+	b.tokens += cost
+	if capacity := b.capacity(); b.tokens > capacity {
+		b.tokens = capacity
+	}
+}
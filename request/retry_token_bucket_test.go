@@ -0,0 +1,79 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-camp/httpc"
+)
+
+type testTimeoutError struct{}
+
+func (e testTimeoutError) Error() string { return "timeout" }
+func (e testTimeoutError) Timeout() bool { return true }
+
+func TestRetryTokenBucket(t *testing.T) {
+	b := &RetryTokenBucket{Capacity: 20, RetryCost: 5, TimeoutCost: 10, SuccessIncrement: 1}
+
+	cost, ok := b.Acquire(errors.New("err"))
+	if !ok || cost != 5 {
+		t.Fatalf("expect cost is 5, got %d, %v", cost, ok)
+	}
+	cost, ok = b.Acquire(testTimeoutError{})
+	if !ok || cost != 10 {
+		t.Fatalf("expect cost is 10, got %d, %v", cost, ok)
+	}
+	if _, ok = b.Acquire(testTimeoutError{}); ok {
+		t.Fatalf("expect acquire failed")
+	}
+	if n := b.Available(); n != 5 {
+		t.Fatalf("expect available is 5, got %d", n)
+	}
+
+	b.Release(10)
+	b.Release(0)
+	if n := b.Available(); n != 16 {
+		t.Fatalf("expect available is 16, got %d", n)
+	}
+	b.Release(10)
+	if n := b.Available(); n != 20 {
+		t.Fatalf("expect available is capped to 20, got %d", n)
+	}
+}
+
+func TestRetryBuilderTokenBucket(t *testing.T) {
+	bucket := &RetryTokenBucket{Capacity: 5}
+	var buildCount int
+	build := RetryBuilder{
+		Retryer: &testRetryer{
+			M: 5,
+			D: func(attempt int) time.Duration { return 0 },
+			C: func(error) Retryable { return RetryableYes },
+		},
+		TokenBucket: bucket,
+	}.Builder(
+		func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+			buildCount++
+			return output, md, errors.New("err")
+		},
+	)
+
+	req := &httpc.Request{
+		Request: &http.Request{URL: &url.URL{}, Header: http.Header{}},
+	}
+	_, _, err := build(context.Background(), req)
+	var qerr *QuotaExceededError
+	if !errors.As(err, &qerr) {
+		t.Fatalf("expect err is %T, got %v", qerr, err)
+	}
+	if qerr.Attempts != 2 || buildCount != 2 {
+		t.Fatalf("expect 2 attempts, got %d, build count %d", qerr.Attempts, buildCount)
+	}
+	if n := bucket.Available(); n != 0 {
+		t.Fatalf("expect available is 0, got %d", n)
+	}
+}
@@ -55,6 +55,8 @@ type ServiceOperationNameInitializer struct {
 	OperationName string
 }
 
+func (ini ServiceOperationNameInitializer) ID() string { return "ServiceOperationNameInitializer" }
+
 func (ini ServiceOperationNameInitializer) Initializer(initialize httpc.InitializeFunc) httpc.InitializeFunc {
 	return func(ctx context.Context, input interface{}) (output interface{}, md httpc.Metadata, err error) {
 		return ini.initialize(ctx, input, initialize)
@@ -79,6 +81,8 @@ func (ini ServiceOperationNameInitializer) initialize(ctx context.Context, input
 // WrapOperationErrorInitializer gets service and operation name from ServiceOperationNameInitializer.
 type WrapOperationErrorInitializer struct{}
 
+func (ini WrapOperationErrorInitializer) ID() string { return "WrapOperationErrorInitializer" }
+
 func (ini WrapOperationErrorInitializer) Initializer(initialize httpc.InitializeFunc) httpc.InitializeFunc {
 	return func(ctx context.Context, input interface{}) (output interface{}, md httpc.Metadata, err error) {
 		return ini.initialize(ctx, input, initialize)
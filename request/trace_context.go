@@ -0,0 +1,315 @@
+package request
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+
+	"github.com/go-camp/httpc"
+	"github.com/go-camp/httpc/response"
+)
+
+const (
+	headerTraceparent   = "Traceparent"
+	headerTracestate    = "Tracestate"
+	headerTraceresponse = "Traceresponse"
+)
+
+// TraceID is a W3C Trace Context trace id.
+type TraceID [16]byte
+
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+// SpanID is a W3C Trace Context parent id.
+type SpanID [8]byte
+
+func (id SpanID) IsValid() bool { return id != SpanID{} }
+
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// TraceFlagsSampled is the sampled flag of TraceFlags.
+const TraceFlagsSampled byte = 0x01
+
+// SpanContext is the propagated part of a span.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	TraceFlags byte
+	// TraceState is the value of tracestate header.
+	TraceState string
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent formats sc as the value of traceparent header.
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, sc.TraceFlags)
+}
+
+// TraceparentError is returned when a traceparent value is malformed.
+type TraceparentError struct {
+	Value string
+}
+
+func (e *TraceparentError) Error() string {
+	return fmt.Sprintf("malformed traceparent %q", e.Value)
+}
+
+func decodeLowerHex(dst []byte, s string) bool {
+	if len(s) != len(dst)*2 || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// ParseTraceparent parses the value of traceparent or traceresponse header.
+func ParseTraceparent(v string) (sc SpanContext, err error) {
+	v = strings.TrimSpace(v)
+	parts := strings.Split(v, "-")
+	if len(parts) < 4 {
+		return sc, &TraceparentError{Value: v}
+	}
+	var version, flags [1]byte
+	if !decodeLowerHex(version[:], parts[0]) || version[0] == 0xff ||
+		(version[0] == 0 && len(parts) != 4) ||
+		!decodeLowerHex(sc.TraceID[:], parts[1]) ||
+		!decodeLowerHex(sc.SpanID[:], parts[2]) ||
+		!decodeLowerHex(flags[:], parts[3]) ||
+		!sc.IsValid() {
+		return SpanContext{}, &TraceparentError{Value: v}
+	}
+	sc.TraceFlags = flags[0]
+	return sc, nil
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a copy of ctx with sc as the current span.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the current span of ctx.
+func SpanContextFromContext(ctx context.Context) (sc SpanContext, ok bool) {
+	sc, ok = ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+// Span is a span started by Tracer.
+type Span interface {
+	SpanContext() SpanContext
+	SetAttribute(key string, value interface{})
+	// End ends the span, err is the error of the span or nil.
+	End(err error)
+}
+
+// Tracer starts spans.
+type Tracer interface {
+	// Start starts a span with the parent, the parent is invalid for a root span.
+	Start(ctx context.Context, name string, parent SpanContext) Span
+}
+
+// NewSpanContext returns a child SpanContext of parent with a new span id,
+// or a root SpanContext with a new trace id and the sampled flag if parent is invalid.
+func NewSpanContext(parent SpanContext) SpanContext {
+	sc := parent
+	if !parent.IsValid() {
+		sc = SpanContext{TraceFlags: TraceFlagsSampled}
+		for !sc.TraceID.IsValid() {
+			randomRead(sc.TraceID[:])
+		}
+	}
+	sc.SpanID = SpanID{}
+	for !sc.SpanID.IsValid() {
+		randomRead(sc.SpanID[:])
+	}
+	return sc
+}
+
+func randomRead(b []byte) {
+	if _, err := crand.Read(b); err != nil {
+		rand.Read(b)
+	}
+}
+
+// NopTracer starts spans which are not recorded, but still have new ids to propagate.
+type NopTracer struct{}
+
+func (NopTracer) Start(ctx context.Context, name string, parent SpanContext) Span {
+	return nopSpan{sc: NewSpanContext(parent)}
+}
+
+type nopSpan struct {
+	sc SpanContext
+}
+
+func (s nopSpan) SpanContext() SpanContext { return s.sc }
+
+func (s nopSpan) SetAttribute(key string, value interface{}) {}
+
+func (s nopSpan) End(err error) {}
+
+type mdTraceKey struct{}
+
+// TraceMetadata records the trace of a call.
+type TraceMetadata struct {
+	// SpanContext is the span of the call started by TraceContextBuilder.
+	SpanContext SpanContext
+	// ServerSpanContext is the span from the traceresponse header of the response, if any.
+	ServerSpanContext SpanContext
+}
+
+// GetTrace gets the trace recorded by TraceContextBuilder from metadata.
+func GetTrace(md httpc.Metadata) (tm TraceMetadata) {
+	v := md.Get(mdTraceKey{})
+	tm, _ = v.(TraceMetadata)
+	return tm
+}
+
+func traceResponse(md httpc.Metadata, err error) *http.Response {
+	var respErr *httpc.ResponseError
+	if errors.As(err, &respErr) && respErr.Response != nil {
+		return respErr.Response
+	}
+	return response.GetResponse(md)
+}
+
+func setStatusCodeAttribute(span Span, md httpc.Metadata, err error) {
+	if resp := traceResponse(md, err); resp != nil {
+		span.SetAttribute("http.status_code", resp.StatusCode)
+	}
+}
+
+// TraceContextBuilder starts a span for the call and injects the W3C Trace Context headers of the span.
+//
+// The span is a child of the span in context set by ContextWithSpanContext,
+// or a root span with new trace and span ids.
+// The span of the call and the server span from the traceresponse header are added to metadata.
+//
+// To start a child span for every attempt of RetryBuilder, add TraceAttemptFinalizer.
+type TraceContextBuilder struct {
+	// If Tracer is nil, then NopTracer is used.
+	Tracer Tracer
+}
+
+func (b TraceContextBuilder) ID() string { return "TraceContextBuilder" }
+
+func (b TraceContextBuilder) Builder(build httpc.BuildFunc) httpc.BuildFunc {
+	return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
+		return b.build(ctx, req, build)
+	}
+}
+
+func (b TraceContextBuilder) tracer() Tracer {
+	if b.Tracer == nil {
+		return NopTracer{}
+	}
+	return b.Tracer
+}
+
+func (b TraceContextBuilder) spanName(ctx context.Context, req *httpc.Request) string {
+	serviceName := GetServiceNameFromContext(ctx)
+	operationName := GetOperationNameFromContext(ctx)
+	if serviceName == "" && operationName == "" {
+		return "HTTP " + requestMethod(req)
+	}
+	return serviceName + "/" + operationName
+}
+
+func requestMethod(req *httpc.Request) string {
+	if req.Method == "" {
+		return http.MethodGet
+	}
+	return req.Method
+}
+
+func injectSpanContext(req *httpc.Request, sc SpanContext) {
+	req.Header.Set(headerTraceparent, sc.Traceparent())
+	if sc.TraceState != "" {
+		req.Header.Set(headerTracestate, sc.TraceState)
+	} else {
+		req.Header.Del(headerTracestate)
+	}
+}
+
+func (b TraceContextBuilder) build(ctx context.Context, req *httpc.Request, build httpc.BuildFunc) (
+	output interface{}, md httpc.Metadata, err error,
+) {
+	parent, _ := SpanContextFromContext(ctx)
+	span := b.tracer().Start(ctx, b.spanName(ctx, req), parent)
+	sc := span.SpanContext()
+	span.SetAttribute("http.method", requestMethod(req))
+	if req.URL != nil {
+		span.SetAttribute("http.url", req.URL.String())
+	}
+	defer func() {
+		setStatusCodeAttribute(span, md, err)
+		span.End(err)
+	}()
+
+	ctx = ContextWithSpanContext(ctx, sc)
+	injectSpanContext(req, sc)
+
+	output, md, err = build(ctx, req)
+
+	tm := TraceMetadata{SpanContext: sc}
+	if resp := traceResponse(md, err); resp != nil {
+		if v := resp.Header.Get(headerTraceresponse); v != "" {
+			tm.ServerSpanContext, _ = ParseTraceparent(v)
+		}
+	}
+	md.Set(mdTraceKey{}, tm)
+	return
+}
+
+// TraceAttemptFinalizer starts a child span of the span in context for every attempt
+// and injects the W3C Trace Context headers of the child span.
+type TraceAttemptFinalizer struct {
+	// If Tracer is nil, then NopTracer is used.
+	Tracer Tracer
+}
+
+func (f TraceAttemptFinalizer) ID() string { return "TraceAttemptFinalizer" }
+
+func (f TraceAttemptFinalizer) Finalizer(finalize httpc.FinalizeFunc) httpc.FinalizeFunc {
+	return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
+		return f.finalize(ctx, req, finalize)
+	}
+}
+
+func (f TraceAttemptFinalizer) tracer() Tracer {
+	if f.Tracer == nil {
+		return NopTracer{}
+	}
+	return f.Tracer
+}
+
+func (f TraceAttemptFinalizer) finalize(ctx context.Context, req *httpc.Request, finalize httpc.FinalizeFunc) (
+	output interface{}, md httpc.Metadata, err error,
+) {
+	parent, _ := SpanContextFromContext(ctx)
+	span := f.tracer().Start(ctx, "HTTP "+requestMethod(req), parent)
+	sc := span.SpanContext()
+	if attempt, _ := GetAttemptFromContext(ctx); attempt > 0 {
+		span.SetAttribute("http.resend_count", attempt-1)
+	}
+	defer func() {
+		setStatusCodeAttribute(span, md, err)
+		span.End(err)
+	}()
+
+	ctx = ContextWithSpanContext(ctx, sc)
+	injectSpanContext(req, sc)
+
+	return finalize(ctx, req)
+}
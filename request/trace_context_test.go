@@ -0,0 +1,216 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/go-camp/httpc"
+	"github.com/go-camp/httpc/response"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	testCases := []struct {
+		Name  string
+		Value string
+
+		ExpectTraceID string
+		ExpectSpanID  string
+		ExpectFlags   byte
+		ExpectError   bool
+	}{
+		{
+			Name:          "valid",
+			Value:         "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			ExpectTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			ExpectSpanID:  "00f067aa0ba902b7",
+			ExpectFlags:   TraceFlagsSampled,
+		},
+		{
+			Name:          "future_version",
+			Value:         "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra",
+			ExpectTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			ExpectSpanID:  "00f067aa0ba902b7",
+		},
+		{
+			Name:        "upper_case",
+			Value:       "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+			ExpectError: true,
+		},
+		{
+			Name:        "zero_trace_id",
+			Value:       "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			ExpectError: true,
+		},
+		{
+			Name:        "invalid_version",
+			Value:       "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			ExpectError: true,
+		},
+		{
+			Name:        "extra_field",
+			Value:       "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			ExpectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			sc, err := ParseTraceparent(tc.Value)
+			if (err != nil) != tc.ExpectError {
+				t.Fatalf("expect err %v, got %v", tc.ExpectError, err)
+			}
+			if tc.ExpectError {
+				return
+			}
+			if sc.TraceID.String() != tc.ExpectTraceID || sc.SpanID.String() != tc.ExpectSpanID ||
+				sc.TraceFlags != tc.ExpectFlags {
+				t.Fatalf("unexpected span context %+v", sc)
+			}
+		})
+	}
+}
+
+type testSpan struct {
+	name   string
+	sc     SpanContext
+	parent SpanContext
+	attrs  map[string]interface{}
+	err    error
+	ended  bool
+}
+
+func (s *testSpan) SpanContext() SpanContext { return s.sc }
+
+func (s *testSpan) SetAttribute(key string, value interface{}) { s.attrs[key] = value }
+
+func (s *testSpan) End(err error) {
+	s.err = err
+	s.ended = true
+}
+
+type testTracer struct {
+	mux   sync.Mutex
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, parent SpanContext) Span {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	span := &testSpan{
+		name:   name,
+		sc:     NewSpanContext(parent),
+		parent: parent,
+		attrs:  map[string]interface{}{},
+	}
+	t.spans = append(t.spans, span)
+	return span
+}
+
+func TestTraceContextBuilder(t *testing.T) {
+	parent, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	parent.TraceState = "vendor=value"
+	serverTraceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-b7ad6b7169203331-01"
+
+	tracer := &testTracer{}
+	var traceparents []string
+	var tracestates []string
+	finalize := TraceAttemptFinalizer{Tracer: tracer}.Finalizer(
+		func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+			traceparents = append(traceparents, req.Header.Get("traceparent"))
+			tracestates = append(tracestates, req.Header.Get("tracestate"))
+			resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+			if len(traceparents) == 1 {
+				resp.StatusCode = http.StatusServiceUnavailable
+				err = &httpc.ResponseError{Response: resp, Err: errors.New("unavailable")}
+				return
+			}
+			resp.Header.Set("traceresponse", serverTraceparent)
+			deserialize := response.ResponseDeserializer{}.Deserializer(
+				func(req *http.Request) (output httpc.DeserializeOutput, md httpc.Metadata, err error) {
+					output.Response = resp
+					return
+				},
+			)
+			_, md, err = deserialize(req.Build())
+			return
+		},
+	)
+	build := httpc.ComposeBuilder(
+		TraceContextBuilder{Tracer: tracer}.Builder,
+		RetryBuilder{
+			Retryer: BasicRetryer{Options: BasicRetryerOptions{Delayer: NopRetryDelayer}},
+		}.Builder,
+	)(httpc.BuildFunc(finalize))
+
+	ctx := ContextWithSpanContext(context.Background(), parent)
+	req := &httpc.Request{
+		Request: &http.Request{Method: http.MethodGet, URL: &url.URL{}, Header: http.Header{}},
+	}
+	_, md, err := build(ctx, req)
+	if err != nil {
+		t.Fatalf("expect no err, got %v", err)
+	}
+
+	if len(tracer.spans) != 3 {
+		t.Fatalf("expect 3 spans, got %d", len(tracer.spans))
+	}
+	callSpan := tracer.spans[0]
+	if callSpan.parent != parent || callSpan.sc.TraceID != parent.TraceID || !callSpan.ended || callSpan.err != nil {
+		t.Fatalf("unexpected call span %+v", callSpan)
+	}
+	for i, attemptSpan := range tracer.spans[1:] {
+		if attemptSpan.parent.SpanID != callSpan.sc.SpanID || attemptSpan.sc.TraceID != parent.TraceID {
+			t.Fatalf("expect attempt span %d is a child of call span, got %+v", i+1, attemptSpan)
+		}
+		if traceparents[i] != attemptSpan.sc.Traceparent() {
+			t.Fatalf("expect traceparent of attempt %d is %s, got %s", i+1, attemptSpan.sc.Traceparent(), traceparents[i])
+		}
+		if tracestates[i] != parent.TraceState {
+			t.Fatalf("expect tracestate of attempt %d is %s, got %s", i+1, parent.TraceState, tracestates[i])
+		}
+		if attemptSpan.attrs["http.resend_count"] != i {
+			t.Fatalf("expect resend count of attempt %d is %d, got %v", i+1, i, attemptSpan.attrs["http.resend_count"])
+		}
+	}
+	if tracer.spans[1].err == nil || tracer.spans[2].err != nil {
+		t.Fatalf("expect only the first attempt span has err")
+	}
+
+	tm := GetTrace(md)
+	if tm.SpanContext != callSpan.sc {
+		t.Fatalf("expect span context of metadata is %+v, got %+v", callSpan.sc, tm.SpanContext)
+	}
+	if tm.ServerSpanContext.Traceparent() != serverTraceparent {
+		t.Fatalf("expect server span context is %s, got %s", serverTraceparent, tm.ServerSpanContext.Traceparent())
+	}
+	if callSpan.attrs["http.status_code"] != http.StatusOK {
+		t.Fatalf("expect status code of call span is 200, got %v", callSpan.attrs["http.status_code"])
+	}
+}
+
+func TestTraceContextBuilderRootSpan(t *testing.T) {
+	build := TraceContextBuilder{}.Builder(
+		func(ctx context.Context, req *httpc.Request) (output interface{}, md httpc.Metadata, err error) {
+			return
+		},
+	)
+
+	req := &httpc.Request{
+		Request: &http.Request{URL: &url.URL{}, Header: http.Header{}},
+	}
+	_, md, err := build(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expect no err, got %v", err)
+	}
+	sc, err := ParseTraceparent(req.Header.Get("traceparent"))
+	if err != nil {
+		t.Fatalf("expect valid traceparent, got %v", err)
+	}
+	if sc.TraceFlags != TraceFlagsSampled || sc != GetTrace(md).SpanContext {
+		t.Fatalf("unexpected span context %+v", sc)
+	}
+}
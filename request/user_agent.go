@@ -20,6 +20,8 @@ type UserAgentBuilder struct {
 	UserAgent UserAgent
 }
 
+func (b UserAgentBuilder) ID() string { return "UserAgentBuilder" }
+
 func (b UserAgentBuilder) Builder(build httpc.BuildFunc) httpc.BuildFunc {
 	return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
 		return b.build(ctx, req, build)
@@ -10,6 +10,8 @@ import (
 type BodyCloseDeserializer struct {
 }
 
+func (d BodyCloseDeserializer) ID() string { return "BodyCloseDeserializer" }
+
 func (d BodyCloseDeserializer) Deserializer(deserialize httpc.DeserializeFunc) httpc.DeserializeFunc {
 	return func(req *http.Request) (httpc.DeserializeOutput, httpc.Metadata, error) {
 		return d.deserialize(req, deserialize)
@@ -36,6 +38,8 @@ func (d BodyCloseDeserializer) deserialize(req *http.Request, deserialize httpc.
 type BodyCloseErrorDeserializer struct {
 }
 
+func (d BodyCloseErrorDeserializer) ID() string { return "BodyCloseErrorDeserializer" }
+
 func (d BodyCloseErrorDeserializer) Deserializer(deserialize httpc.DeserializeFunc) httpc.DeserializeFunc {
 	return func(req *http.Request) (httpc.DeserializeOutput, httpc.Metadata, error) {
 		return d.deserialize(req, deserialize)
@@ -14,6 +14,8 @@ const maxBodyDiscardBytes = 2 << 10
 type BodyDiscardDeserializer struct {
 }
 
+func (d BodyDiscardDeserializer) ID() string { return "BodyDiscardDeserializer" }
+
 func (d BodyDiscardDeserializer) Deserializer(deserialize httpc.DeserializeFunc) httpc.DeserializeFunc {
 	return func(req *http.Request) (httpc.DeserializeOutput, httpc.Metadata, error) {
 		return d.deserialize(req, deserialize)
@@ -48,6 +50,8 @@ func (d BodyDiscardDeserializer) deserialize(req *http.Request, deserialize http
 type BodyDiscardErrorDeserializer struct {
 }
 
+func (d BodyDiscardErrorDeserializer) ID() string { return "BodyDiscardErrorDeserializer" }
+
 func (d BodyDiscardErrorDeserializer) Deserializer(deserialize httpc.DeserializeFunc) httpc.DeserializeFunc {
 	return func(req *http.Request) (httpc.DeserializeOutput, httpc.Metadata, error) {
 		return d.deserialize(req, deserialize)
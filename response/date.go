@@ -21,6 +21,8 @@ type DateDeserializer struct {
 	ParseTime func(string) (time.Time, error)
 }
 
+func (d DateDeserializer) ID() string { return "DateDeserializer" }
+
 func (d DateDeserializer) Deserializer(deserialize httpc.DeserializeFunc) httpc.DeserializeFunc {
 	return func(req *http.Request) (httpc.DeserializeOutput, httpc.Metadata, error) {
 		return d.deserialize(req, deserialize)
@@ -0,0 +1,109 @@
+package response
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/go-camp/httpc"
+)
+
+// DumpEnv is the environment variable to enable the dump,
+// the dump is enabled if its value is true according to strconv.ParseBool.
+const DumpEnv = "HTTPC_DUMP"
+
+// DumpEnabledByEnv reports whether the dump is enabled by DumpEnv.
+func DumpEnabledByEnv() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(DumpEnv))
+	return enabled
+}
+
+// Dump is the dump state of a call, it is added to the request context by request.DumpBuilder.
+type Dump struct {
+	// Writer is where the dumps are written.
+	Writer io.Writer
+	// Body reports whether the request and response bodies are dumped.
+	Body bool
+	// RequestBody is the request body, which is read by request.DumpBuilder.
+	RequestBody []byte
+
+	mux sync.Mutex
+}
+
+type dumpKey struct{}
+
+// ContextWithDump returns a copy of ctx with the dump state.
+func ContextWithDump(ctx context.Context, d *Dump) context.Context {
+	return context.WithValue(ctx, dumpKey{}, d)
+}
+
+// DumpFromContext returns the dump state of ctx.
+func DumpFromContext(ctx context.Context) *Dump {
+	d, _ := ctx.Value(dumpKey{}).(*Dump)
+	return d
+}
+
+func (d *Dump) write(dump []byte) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	d.Writer.Write(dump)
+}
+
+// DumpDeserializer writes the wire format of every request and response
+// in the format of httputil.DumpRequestOut and httputil.DumpResponse,
+// if the request context has the dump state of request.DumpBuilder.
+//
+// DumpDeserializer should be the last Deserializer, so that the dump is what is sent and received.
+// The response body is re-wrapped after dumped, so the later Deserializers read the full body.
+type DumpDeserializer struct{}
+
+func (d DumpDeserializer) ID() string { return "DumpDeserializer" }
+
+func (d DumpDeserializer) Deserializer(deserialize httpc.DeserializeFunc) httpc.DeserializeFunc {
+	return func(req *http.Request) (httpc.DeserializeOutput, httpc.Metadata, error) {
+		return d.deserialize(req, deserialize)
+	}
+}
+
+func (d DumpDeserializer) deserialize(req *http.Request, deserialize httpc.DeserializeFunc) (
+	output httpc.DeserializeOutput, md httpc.Metadata, err error,
+) {
+	dump := DumpFromContext(req.Context())
+	if dump == nil || dump.Writer == nil {
+		return deserialize(req)
+	}
+
+	var buf bytes.Buffer
+	reqDump, derr := httputil.DumpRequestOut(req, false)
+	if derr != nil {
+		fmt.Fprintf(&buf, "dump request failed, %v\n", derr)
+	} else {
+		buf.Write(reqDump)
+		if dump.Body {
+			buf.Write(dump.RequestBody)
+		}
+	}
+	buf.WriteString("\n\n")
+
+	output, md, err = deserialize(req)
+	if err != nil {
+		fmt.Fprintf(&buf, "send request failed, %v\n\n", err)
+	}
+	if resp := output.Response; resp != nil {
+		respDump, derr := httputil.DumpResponse(resp, dump.Body)
+		if derr != nil {
+			fmt.Fprintf(&buf, "dump response failed, %v\n", derr)
+		} else {
+			buf.Write(respDump)
+		}
+		buf.WriteString("\n\n")
+	}
+	dump.write(buf.Bytes())
+	return
+}
@@ -0,0 +1,66 @@
+package response
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-camp/httpc"
+)
+
+func TestDumpDeserializer(t *testing.T) {
+	testCases := []struct {
+		Name string
+		Dump *Dump
+
+		ExpectDump string
+	}{
+		{
+			Name: "without_dump",
+		},
+		{
+			Name: "dump",
+			Dump: &Dump{Body: true, RequestBody: []byte("request body")},
+			ExpectDump: "GET / HTTP/1.1\r\nHost: example.com\r\nUser-Agent: Go-http-client/1.1\r\n" +
+				"Accept-Encoding: gzip\r\n\r\nrequest body\n\n" +
+				"HTTP/1.1 200 OK\r\nContent-Length: 13\r\n\r\nresponse body\n\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			req, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+			if tc.Dump != nil {
+				tc.Dump.Writer = &buf
+				req = req.WithContext(ContextWithDump(req.Context(), tc.Dump))
+			}
+			deserialize := DumpDeserializer{}.Deserializer(
+				func(req *http.Request) (output httpc.DeserializeOutput, md httpc.Metadata, err error) {
+					output.Response = &http.Response{
+						StatusCode:    http.StatusOK,
+						ProtoMajor:    1,
+						ProtoMinor:    1,
+						ContentLength: 13,
+						Body:          io.NopCloser(strings.NewReader("response body")),
+					}
+					return
+				},
+			)
+			output, _, err := deserialize(req)
+			if err != nil {
+				t.Fatalf("expect no err, got %v", err)
+			}
+
+			if buf.String() != tc.ExpectDump {
+				t.Fatalf("expect dump is %q, got %q", tc.ExpectDump, buf.String())
+			}
+			body, _ := io.ReadAll(output.Response.Body)
+			if string(body) != "response body" {
+				t.Fatalf("expect response body is not consumed, got %s", body)
+			}
+		})
+	}
+}
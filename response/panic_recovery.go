@@ -0,0 +1,24 @@
+package response
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-camp/httpc"
+)
+
+// PanicRecoveryDeserializer recovers a panic of the wrapped DeserializeFunc and returns it as PanicError.
+type PanicRecoveryDeserializer struct{}
+
+func (d PanicRecoveryDeserializer) ID() string { return "PanicRecoveryDeserializer" }
+
+func (d PanicRecoveryDeserializer) Deserializer(deserialize httpc.DeserializeFunc) httpc.DeserializeFunc {
+	return func(req *http.Request) (output httpc.DeserializeOutput, md httpc.Metadata, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = &httpc.PanicError{Stage: httpc.StageDeserialize, Value: r, Stack: debug.Stack()}
+			}
+		}()
+		return deserialize(req)
+	}
+}
@@ -0,0 +1,33 @@
+package response
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-camp/httpc"
+)
+
+func TestPanicRecoveryDeserializer(t *testing.T) {
+	panicErr := errors.New("deserialize panic")
+	deserialize := PanicRecoveryDeserializer{}.Deserializer(
+		func(req *http.Request) (output httpc.DeserializeOutput, md httpc.Metadata, err error) {
+			panic(panicErr)
+		},
+	)
+	_, _, err := deserialize(newNopHTTPRequest())
+	var perr *httpc.PanicError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expect err is %T, got %v", perr, err)
+	}
+	if perr.Stage != httpc.StageDeserialize {
+		t.Fatalf("expect stage is %s, got %s", httpc.StageDeserialize, perr.Stage)
+	}
+	if !errors.Is(err, panicErr) {
+		t.Fatalf("expect err wraps %v, got %v", panicErr, err)
+	}
+	expectError := "Deserialize stage panic: deserialize panic"
+	if expectError != err.Error() {
+		t.Fatalf("expect err is %s, got %s", expectError, err)
+	}
+}
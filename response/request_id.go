@@ -20,6 +20,8 @@ type RequestIDDeserializer struct {
 	Headers []string
 }
 
+func (d RequestIDDeserializer) ID() string { return "RequestIDDeserializer" }
+
 func (d RequestIDDeserializer) Deserializer(deserialize httpc.DeserializeFunc) httpc.DeserializeFunc {
 	return func(req *http.Request) (httpc.DeserializeOutput, httpc.Metadata, error) {
 		return d.deserialize(req, deserialize)
@@ -19,6 +19,8 @@ func GetResponse(md httpc.Metadata) *http.Response {
 type ResponseDeserializer struct {
 }
 
+func (d ResponseDeserializer) ID() string { return "ResponseDeserializer" }
+
 func (d ResponseDeserializer) Deserializer(deserialize httpc.DeserializeFunc) httpc.DeserializeFunc {
 	return func(req *http.Request) (httpc.DeserializeOutput, httpc.Metadata, error) {
 		return d.deserialize(req, deserialize)
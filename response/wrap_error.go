@@ -10,6 +10,8 @@ import (
 type WrapRequestErrorDeserializer struct {
 }
 
+func (d WrapRequestErrorDeserializer) ID() string { return "WrapRequestErrorDeserializer" }
+
 func (d WrapRequestErrorDeserializer) Deserializer(deserialize httpc.DeserializeFunc) httpc.DeserializeFunc {
 	return func(req *http.Request) (httpc.DeserializeOutput, httpc.Metadata, error) {
 		return d.deserialize(req, deserialize)
@@ -32,6 +34,8 @@ func (d WrapRequestErrorDeserializer) deserialize(req *http.Request, deserialize
 type WrapResponseErrorDeserializer struct {
 }
 
+func (d WrapResponseErrorDeserializer) ID() string { return "WrapResponseErrorDeserializer" }
+
 func (d WrapResponseErrorDeserializer) Deserializer(deserialize httpc.DeserializeFunc) httpc.DeserializeFunc {
 	return func(req *http.Request) (httpc.DeserializeOutput, httpc.Metadata, error) {
 		return d.deserialize(req, deserialize)
@@ -0,0 +1,87 @@
+package signer
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-camp/httpc"
+)
+
+// DefaultClockSkewThreshold is the default threshold of ClockSkewDetector.
+const DefaultClockSkewThreshold = 1 * time.Minute
+
+// ClockSkewDetector is a ClockSkewer which detects the clock skew from the Date header of responses.
+//
+// The Deserializer of ClockSkewDetector records the difference between
+// the server time and the local time when a response is received,
+// it is usually shared by the SigV4Finalizers of a client.
+// A difference smaller than Threshold is ignored,
+// since the Date header only has a precision of seconds.
+type ClockSkewDetector struct {
+	// If Threshold is 0, then DefaultClockSkewThreshold is used.
+	Threshold time.Duration
+	// If Now is nil, then time.Now is used.
+	Now func() time.Time
+
+	skew int64
+}
+
+var _ ClockSkewer = (*ClockSkewDetector)(nil)
+
+func (d *ClockSkewDetector) threshold() time.Duration {
+	if d.Threshold == 0 {
+		return DefaultClockSkewThreshold
+	}
+	return d.Threshold
+}
+
+func (d *ClockSkewDetector) now() time.Time {
+	if d.Now == nil {
+		return time.Now()
+	}
+	return d.Now()
+}
+
+func (d *ClockSkewDetector) ClockSkew() time.Duration {
+	return time.Duration(atomic.LoadInt64(&d.skew))
+}
+
+// Observe records the clock skew from serverTime.
+func (d *ClockSkewDetector) Observe(serverTime time.Time) {
+	skew := serverTime.Sub(d.now())
+	if skew < d.threshold() && skew > -d.threshold() {
+		skew = 0
+	}
+	atomic.StoreInt64(&d.skew, int64(skew))
+}
+
+func (d *ClockSkewDetector) ID() string { return "ClockSkewDetector" }
+
+func (d *ClockSkewDetector) Deserializer(deserialize httpc.DeserializeFunc) httpc.DeserializeFunc {
+	return func(req *http.Request) (httpc.DeserializeOutput, httpc.Metadata, error) {
+		return d.deserialize(req, deserialize)
+	}
+}
+
+func (d *ClockSkewDetector) deserialize(req *http.Request, deserialize httpc.DeserializeFunc) (
+	output httpc.DeserializeOutput, md httpc.Metadata, err error,
+) {
+	output, md, err = deserialize(req)
+
+	resp := output.Response
+	var respErr *httpc.ResponseError
+	if resp == nil && errors.As(err, &respErr) {
+		resp = respErr.Response
+	}
+	if resp == nil {
+		return
+	}
+	serverTime, perr := http.ParseTime(resp.Header.Get("Date"))
+	if perr != nil {
+		return
+	}
+	d.Observe(serverTime)
+	return
+}
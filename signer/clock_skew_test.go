@@ -0,0 +1,58 @@
+package signer
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-camp/httpc"
+)
+
+func TestClockSkewDetector(t *testing.T) {
+	testCases := []struct {
+		Name       string
+		StatusCode int
+		Date       string
+
+		ExpectClockSkew time.Duration
+	}{
+		{Name: "server ahead", StatusCode: http.StatusOK, Date: "Sun, 30 Aug 2015 12:46:00 GMT", ExpectClockSkew: 10 * time.Minute},
+		{Name: "server behind", StatusCode: http.StatusForbidden, Date: "Sun, 30 Aug 2015 12:26:00 GMT", ExpectClockSkew: -10 * time.Minute},
+		{Name: "within threshold", StatusCode: http.StatusOK, Date: "Sun, 30 Aug 2015 12:36:30 GMT", ExpectClockSkew: 0},
+		{Name: "no date", StatusCode: http.StatusOK, Date: "", ExpectClockSkew: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			d := &ClockSkewDetector{Now: func() time.Time { return testTime }}
+			deserialize := d.Deserializer(func(req *http.Request) (output httpc.DeserializeOutput, md httpc.Metadata, err error) {
+				output.Response = &http.Response{
+					StatusCode: tc.StatusCode,
+					Header:     http.Header{},
+					Body:       io.NopCloser(strings.NewReader("")),
+				}
+				if tc.Date != "" {
+					output.Response.Header.Set("Date", tc.Date)
+				}
+				if tc.StatusCode != http.StatusOK {
+					err = &httpc.ResponseError{Response: output.Response, Err: io.ErrUnexpectedEOF}
+					output.Response = nil
+				}
+				return
+			})
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "https://example.amazonaws.com/", nil)
+			deserialize(req)
+
+			if skew := d.ClockSkew(); skew != tc.ExpectClockSkew {
+				t.Fatalf("expect clock skew is %v, got %v", tc.ExpectClockSkew, skew)
+			}
+			b := SigV4Finalizer{Now: func() time.Time { return testTime }, ClockSkew: d}
+			if now := b.now(); !now.Equal(testTime.Add(tc.ExpectClockSkew)) {
+				t.Fatalf("expect signing time is %v, got %v", testTime.Add(tc.ExpectClockSkew), now)
+			}
+		})
+	}
+}
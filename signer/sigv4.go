@@ -0,0 +1,399 @@
+// Package signer provides the request signers of httpc.
+package signer
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-camp/httpc"
+	"github.com/go-camp/httpc/request"
+)
+
+const (
+	sigV4Algorithm   = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat  = "20060102T150405Z"
+	sigV4DateFormat  = "20060102"
+	sigV4Termination = "aws4_request"
+
+	// UnsignedPayload is the payload hash of a request whose body is not signed.
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+	// EmptyPayloadHash is the sha256 hash of an empty payload.
+	EmptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+const (
+	headerAuthorization     = "Authorization"
+	headerXAmzDate          = "X-Amz-Date"
+	headerXAmzContentSHA256 = "X-Amz-Content-Sha256"
+	headerXAmzSecurityToken = "X-Amz-Security-Token"
+)
+
+// sigV4IgnoredHeaders are not signed, since they may be changed by proxies or the transport.
+var sigV4IgnoredHeaders = map[string]bool{
+	"authorization":     true,
+	"user-agent":        true,
+	"x-amz-user-agent":  true,
+	"expect":            true,
+	"transfer-encoding": true,
+	"x-amzn-trace-id":   true,
+}
+
+// Credentials are the AWS credentials.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is the token of temporary credentials, it is sent by the X-Amz-Security-Token header.
+	SessionToken string
+}
+
+// CredentialsProvider provides credentials.
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// StaticCredentialsProvider always provides the same credentials.
+type StaticCredentialsProvider struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+func (p StaticCredentialsProvider) Credentials(ctx context.Context) (Credentials, error) {
+	return Credentials(p), nil
+}
+
+// ClockSkewer is the clock skew correction hook of SigV4Finalizer.
+type ClockSkewer interface {
+	// ClockSkew returns the offset added to the local time.
+	ClockSkew() time.Duration
+}
+
+// SigV4Finalizer signs every attempt of the request with AWS Signature Version 4.
+//
+// SigV4Finalizer must be the last Finalizer, since all the request headers except a few
+// such as User-Agent are signed, and the headers set after signing, e.g. the attempt headers, break the signature.
+// The payload hash is calculated from the request Body, which must implement io.Seeker interface,
+// a non-seekable Body can be made seekable by BodyBufferBuilder.
+// The payload hash is taken from the X-Amz-Content-Sha256 header if it is already set.
+type SigV4Finalizer struct {
+	Credentials CredentialsProvider
+	Region      string
+	Service     string
+	// UnsignedPayload sends UNSIGNED-PAYLOAD as the payload hash instead of hashing the Body.
+	// The X-Amz-Content-Sha256 header is set.
+	UnsignedPayload bool
+	// ContentSHA256Header sets the payload hash to the X-Amz-Content-Sha256 header, it is required by S3.
+	ContentSHA256Header bool
+	// DisableURIPathEscaping signs the escaped path as is instead of normalizing and escaping it again,
+	// it is required by S3.
+	DisableURIPathEscaping bool
+	// If Now is nil, then time.Now is used.
+	Now func() time.Time
+	// ClockSkew corrects the signing time if it is not nil.
+	ClockSkew ClockSkewer
+}
+
+type sigV4Error struct {
+	While string
+	Err   error
+}
+
+func (e *sigV4Error) Error() string {
+	return fmt.Sprintf("request sigv4 finalizer, %s failed, %v", e.While, e.Err)
+}
+
+func (e *sigV4Error) Unwrap() error {
+	return e.Err
+}
+
+func (b SigV4Finalizer) ID() string { return "SigV4Finalizer" }
+
+func (b SigV4Finalizer) Finalizer(finalize httpc.FinalizeFunc) httpc.FinalizeFunc {
+	return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
+		return b.finalize(ctx, req, finalize)
+	}
+}
+
+func (b SigV4Finalizer) now() time.Time {
+	var now time.Time
+	if b.Now == nil {
+		now = time.Now()
+	} else {
+		now = b.Now()
+	}
+	if b.ClockSkew != nil {
+		now = now.Add(b.ClockSkew.ClockSkew())
+	}
+	return now.UTC()
+}
+
+func (b SigV4Finalizer) finalize(ctx context.Context, req *httpc.Request, finalize httpc.FinalizeFunc) (
+	output interface{}, md httpc.Metadata, err error,
+) {
+	creds, err := b.Credentials.Credentials(ctx)
+	if err != nil {
+		return output, md, &sigV4Error{While: "get credentials", Err: err}
+	}
+	payloadHash, err := b.payloadHash(req)
+	if err != nil {
+		return output, md, &sigV4Error{While: "hash payload", Err: err}
+	}
+	if err = b.sign(req, creds, payloadHash, b.now()); err != nil {
+		return output, md, &sigV4Error{While: "sign request", Err: err}
+	}
+	return finalize(ctx, req)
+}
+
+func (b SigV4Finalizer) payloadHash(req *httpc.Request) (payloadHash string, err error) {
+	if v := req.Header.Get(headerXAmzContentSHA256); v != "" {
+		return v, nil
+	}
+	if b.UnsignedPayload {
+		return UnsignedPayload, nil
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		return EmptyPayloadHash, nil
+	}
+	err = request.ReadRewind(req.Body, func(r io.Reader) error {
+		h := sha256.New()
+		if _, err := io.Copy(h, r); err != nil {
+			return err
+		}
+		payloadHash = hex.EncodeToString(h.Sum(nil))
+		return nil
+	})
+	return
+}
+
+func (b SigV4Finalizer) sign(req *httpc.Request, creds Credentials, payloadHash string, now time.Time) error {
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return fmt.Errorf("access key id or secret access key is empty")
+	}
+
+	req.Header.Del(headerAuthorization)
+	req.Header.Set(headerXAmzDate, now.Format(sigV4TimeFormat))
+	if creds.SessionToken != "" {
+		req.Header.Set(headerXAmzSecurityToken, creds.SessionToken)
+	}
+	if b.UnsignedPayload || b.ContentSHA256Header {
+		req.Header.Set(headerXAmzContentSHA256, payloadHash)
+	}
+
+	signedHeaders, canonicalHeaders := canonicalHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		b.canonicalURI(req.URL),
+		canonicalQuery(req.URL),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	date := now.Format(sigV4DateFormat)
+	scope := strings.Join([]string{date, b.Region, b.Service, sigV4Termination}, "/")
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		now.Format(sigV4TimeFormat),
+		scope,
+		hashHex(canonicalRequest),
+	}, "\n")
+
+	key := signingKeys.get(creds.SecretAccessKey, date, b.Region, b.Service)
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set(headerAuthorization, sigV4Algorithm+
+		" Credential="+creds.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+
+		", Signature="+signature)
+	return nil
+}
+
+func (b SigV4Finalizer) canonicalURI(u *url.URL) string {
+	uri := u.Opaque
+	if uri != "" {
+		// an opaque of //host/path is used as the path.
+		if strings.HasPrefix(uri, "//") {
+			if i := strings.Index(uri[2:], "/"); i >= 0 {
+				uri = uri[2+i:]
+			} else {
+				uri = ""
+			}
+		}
+	} else {
+		uri = u.EscapedPath()
+	}
+	if uri == "" {
+		uri = "/"
+	}
+	if !b.DisableURIPathEscaping {
+		uri = escape(normalizePath(uri), false)
+	}
+	return uri
+}
+
+// normalizePath removes the dot segments and the duplicate slashes of uri, the trailing slash is kept.
+func normalizePath(uri string) string {
+	clean := path.Clean(uri)
+	if clean != "/" && strings.HasSuffix(uri, "/") {
+		clean += "/"
+	}
+	return clean
+}
+
+func canonicalQuery(u *url.URL) string {
+	if u.RawQuery == "" {
+		return ""
+	}
+	query := u.Query()
+	pairs := make([][2]string, 0, len(query))
+	for k, vs := range query {
+		ek := escape(k, true)
+		for _, v := range vs {
+			pairs = append(pairs, [2]string{ek, escape(v, true)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i][0] < pairs[j][0] || (pairs[i][0] == pairs[j][0] && pairs[i][1] < pairs[j][1])
+	})
+
+	var sb strings.Builder
+	for i, pair := range pairs {
+		if i > 0 {
+			sb.WriteByte('&')
+		}
+		sb.WriteString(pair[0])
+		sb.WriteByte('=')
+		sb.WriteString(pair[1])
+	}
+	return sb.String()
+}
+
+func canonicalHeaders(req *httpc.Request) (signedHeaders string, canonicalHeaders string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	host = stripDefaultPort(req.URL.Scheme, host)
+
+	values := map[string][]string{"host": {host}}
+	names := []string{"host"}
+	for k, vs := range req.Header {
+		name := strings.ToLower(k)
+		if name == "host" || sigV4IgnoredHeaders[name] {
+			continue
+		}
+		if _, ok := values[name]; !ok {
+			names = append(names, name)
+		}
+		values[name] = append(values[name], vs...)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		sb.WriteString(name)
+		sb.WriteByte(':')
+		for i, v := range values[name] {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(strings.Join(strings.Fields(v), " "))
+		}
+		sb.WriteByte('\n')
+	}
+	return strings.Join(names, ";"), sb.String()
+}
+
+func stripDefaultPort(scheme, host string) string {
+	switch {
+	case scheme == "http" && strings.HasSuffix(host, ":80"):
+		return strings.TrimSuffix(host, ":80")
+	case scheme == "https" && strings.HasSuffix(host, ":443"):
+		return strings.TrimSuffix(host, ":443")
+	}
+	return host
+}
+
+// escape percent-encodes s except the unreserved characters of RFC 3986,
+// '/' is kept unless encodeSlash is true.
+func escape(s string, encodeSlash bool) string {
+	const hexUpper = "0123456789ABCDEF"
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			sb.WriteByte(c)
+			continue
+		}
+		sb.WriteByte('%')
+		sb.WriteByte(hexUpper[c>>4])
+		sb.WriteByte(hexUpper[c&15])
+	}
+	return sb.String()
+}
+
+func hashHex(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+type signingKeyID struct {
+	secret  string
+	date    string
+	region  string
+	service string
+}
+
+// signingKeyCache caches the signing keys of the current date.
+type signingKeyCache struct {
+	mux  sync.Mutex
+	keys map[signingKeyID][]byte
+}
+
+var signingKeys = &signingKeyCache{}
+
+func (c *signingKeyCache) get(secret, date, region, service string) []byte {
+	id := signingKeyID{secret: secret, date: date, region: region, service: service}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if key, ok := c.keys[id]; ok {
+		return key
+	}
+
+	for k := range c.keys {
+		if k.date != date {
+			delete(c.keys, k)
+		}
+	}
+	if c.keys == nil {
+		c.keys = map[signingKeyID][]byte{}
+	}
+	key := deriveSigningKey(secret, date, region, service)
+	c.keys[id] = key
+	return key
+}
+
+func deriveSigningKey(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, sigV4Termination)
+}
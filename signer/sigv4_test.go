@@ -0,0 +1,403 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-camp/httpc"
+	"github.com/go-camp/httpc/request"
+)
+
+var (
+	testCredentials = StaticCredentialsProvider{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	testTime = time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+)
+
+func newSigV4TestHandler(method, url string, body io.Reader, header http.Header,
+	finalizer httpc.Finalizer, builders ...httpc.Builder,
+) (httpc.Handler, *[]*http.Request) {
+	var sent []*http.Request
+	return httpc.Handler{
+		Serializer: func(serialize httpc.SerializeFunc) httpc.SerializeFunc {
+			return func(ctx context.Context, input httpc.SerializeInput) (output interface{}, md httpc.Metadata, err error) {
+				input.Request, err = httpc.NewRequest(ctx, method, url, body)
+				if err != nil {
+					return
+				}
+				for k, vs := range header {
+					input.Request.Header[k] = vs
+				}
+				return serialize(ctx, input)
+			}
+		},
+		Builder:   httpc.ComposeBuilder(builders...),
+		Finalizer: finalizer,
+		Do: func(req *http.Request) (*http.Response, error) {
+			if req.Body != nil {
+				b, _ := io.ReadAll(req.Body)
+				req.Body = io.NopCloser(bytes.NewReader(b))
+			}
+			sent = append(sent, req)
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader("")),
+			}, nil
+		},
+	}, &sent
+}
+
+// The test cases are from the AWS Signature Version 4 test suite.
+func TestSigV4FinalizerTestSuite(t *testing.T) {
+	const unreserved = "-._~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ_abcdefghijklmnopqrstuvwxyz"
+	const stsToken = "AQoDYXdzEPT//////////wEXAMPLEtc764bNrC9SAPBSM22wDOk4x4HIZ8j4FZTwdQWLWsKWHGBuFqwAeMicRXmxfpSPfIeoIYRqTfl" +
+		"fKD8YUuwthAx7mSEI/qkPpKPi/kMcGdQrmGdeehM4IC1NtBmUpp2wUE8phUZampKsburEDy0KPkyQDYwT7WZ0wq5VSXDvp75YU9HFvlRd8Tx6q6f" +
+		"E8YQcHNVXAkiY9q6d+xo0rKwT38xVqr7ZD0u0iPPkUL64lIZbqBAz+scqKmlzm8FDrypNC9Yjc8fPOLn9FX9KSYvKTr4rvx3iSIlTJabIQwj2ICCR/oLxBA=="
+
+	testCases := []struct {
+		Name   string
+		Method string
+		URL    string
+		// Opaque is the raw request path of the test suite, which is not escaped before signing.
+		Opaque string
+		Body   string
+		Header http.Header
+
+		ExpectAuthorization string
+	}{
+		{
+			Name:   "get-vanilla",
+			Method: http.MethodGet,
+			URL:    "https://example.amazonaws.com/",
+			ExpectAuthorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;x-amz-date, " +
+				"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			Name:   "normalize-path/get-relative",
+			Method: http.MethodGet,
+			URL:    "https://example.amazonaws.com/example/..",
+			ExpectAuthorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;x-amz-date, " +
+				"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			Name:   "normalize-path/get-relative-relative",
+			Method: http.MethodGet,
+			URL:    "https://example.amazonaws.com/example1/example2/../..",
+			ExpectAuthorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;x-amz-date, " +
+				"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			Name:   "normalize-path/get-slash",
+			Method: http.MethodGet,
+			URL:    "https://example.amazonaws.com//",
+			ExpectAuthorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;x-amz-date, " +
+				"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			Name:   "normalize-path/get-slash-dot-slash",
+			Method: http.MethodGet,
+			URL:    "https://example.amazonaws.com/./",
+			ExpectAuthorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;x-amz-date, " +
+				"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			Name:   "normalize-path/get-slash-pointless-dot",
+			Method: http.MethodGet,
+			URL:    "https://example.amazonaws.com/./example",
+			ExpectAuthorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;x-amz-date, " +
+				"Signature=ef75d96142cf21edca26f06005da7988e4f8dc83a165a80865db7089db637ec5",
+		},
+		{
+			Name:   "normalize-path/get-slashes",
+			Method: http.MethodGet,
+			URL:    "https://example.amazonaws.com//example//",
+			ExpectAuthorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;x-amz-date, " +
+				"Signature=9a624bd73a37c9a373b5312afbebe7a714a789de108f0bdfe846570885f57e84",
+		},
+		{
+			Name:   "get-header-value-trim",
+			Method: http.MethodGet,
+			URL:    "https://example.amazonaws.com/",
+			Header: http.Header{"My-Header1": {" value1"}, "My-Header2": {` "a   b   c"`}},
+			ExpectAuthorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;my-header1;my-header2;x-amz-date, " +
+				"Signature=acc3ed3afb60bb290fc8d2dd0098b9911fcaa05412b367055dee359757a9c736",
+		},
+		{
+			Name:   "get-utf8",
+			Method: http.MethodGet,
+			URL:    "https://example.amazonaws.com/",
+			Opaque: "/\u1234",
+			ExpectAuthorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;x-amz-date, " +
+				"Signature=8318018e0b0f223aa2bbf98705b62bb787dc9c0e678f255a891fd03141be5d85",
+		},
+		{
+			Name:   "get-space",
+			Method: http.MethodGet,
+			URL:    "https://example.amazonaws.com/",
+			Opaque: "/example space/",
+			ExpectAuthorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;x-amz-date, " +
+				"Signature=652487583200325589f1fba4c7e578f72c47cb61beeca81406b39ddec1366741",
+		},
+		{
+			Name:   "get-vanilla-query-unreserved",
+			Method: http.MethodGet,
+			URL:    "https://example.amazonaws.com/?" + unreserved + "=" + unreserved,
+			ExpectAuthorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;x-amz-date, " +
+				"Signature=c0e2549664ab6caf8a0e49ec520df161cca33ec1de41067db4994a4467d458ff",
+		},
+		{
+			Name:   "get-vanilla-query-order-key-case",
+			Method: http.MethodGet,
+			URL:    "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			ExpectAuthorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;x-amz-date, " +
+				"Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+		{
+			Name:   "post-vanilla",
+			Method: http.MethodPost,
+			URL:    "https://example.amazonaws.com/",
+			ExpectAuthorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;x-amz-date, " +
+				"Signature=5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+		{
+			Name:   "post-header-key-sort",
+			Method: http.MethodPost,
+			URL:    "https://example.amazonaws.com/",
+			Header: http.Header{"My-Header1": {"value1"}},
+			ExpectAuthorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;my-header1;x-amz-date, " +
+				"Signature=c5410059b04c1ee005303aed430f6e6645f61f4dc9e1461ec8f8916fdf18852c",
+		},
+		{
+			Name:   "post-sts-token/post-sts-header-before",
+			Method: http.MethodPost,
+			URL:    "https://example.amazonaws.com/",
+			Header: http.Header{"X-Amz-Security-Token": {stsToken}},
+			ExpectAuthorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;x-amz-date;x-amz-security-token, " +
+				"Signature=85d96828115b5dc0cfc3bd16ad9e210dd772bbebba041836c64533a82be05ead",
+		},
+		{
+			Name:   "post-x-www-form-urlencoded",
+			Method: http.MethodPost,
+			URL:    "https://example.amazonaws.com/",
+			Body:   "Param1=value1",
+			Header: http.Header{"Content-Type": {"application/x-www-form-urlencoded"}},
+			ExpectAuthorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=content-type;host;x-amz-date, " +
+				"Signature=ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			b := SigV4Finalizer{
+				Credentials: testCredentials,
+				Region:      "us-east-1",
+				Service:     "service",
+				Now:         func() time.Time { return testTime },
+			}
+			opaque := func(build httpc.BuildFunc) httpc.BuildFunc {
+				return func(ctx context.Context, req *httpc.Request) (interface{}, httpc.Metadata, error) {
+					req.URL.Opaque = tc.Opaque
+					return build(ctx, req)
+				}
+			}
+			h, sent := newSigV4TestHandler(tc.Method, tc.URL, strings.NewReader(tc.Body), tc.Header, b.Finalizer, opaque)
+			_, _, err := h.Handle(context.Background(), nil)
+			if err != nil {
+				t.Fatalf("expect no err, got %v", err)
+			}
+
+			req := (*sent)[0]
+			if v := req.Header.Get("X-Amz-Date"); v != "20150830T123600Z" {
+				t.Fatalf("expect x-amz-date is 20150830T123600Z, got %s", v)
+			}
+			if v := req.Header.Get("Authorization"); v != tc.ExpectAuthorization {
+				t.Fatalf("expect authorization is %s, got %s", tc.ExpectAuthorization, v)
+			}
+			body, _ := io.ReadAll(req.Body)
+			if string(body) != tc.Body {
+				t.Fatalf("expect body is %q, got %q", tc.Body, body)
+			}
+		})
+	}
+}
+
+func TestSigV4FinalizerPayload(t *testing.T) {
+	bodyHash := sha256.Sum256([]byte("payload"))
+
+	testCases := []struct {
+		Name      string
+		Finalizer SigV4Finalizer
+		Body      io.Reader
+		Header    http.Header
+
+		ExpectErr           bool
+		ExpectContentSHA256 string
+	}{
+		{
+			Name:                "unsigned payload",
+			Finalizer:           SigV4Finalizer{UnsignedPayload: true},
+			Body:                io.LimitReader(strings.NewReader("payload"), 7),
+			ExpectContentSHA256: UnsignedPayload,
+		},
+		{
+			Name:                "content sha256 header",
+			Finalizer:           SigV4Finalizer{ContentSHA256Header: true},
+			Body:                strings.NewReader("payload"),
+			ExpectContentSHA256: hex.EncodeToString(bodyHash[:]),
+		},
+		{
+			Name:                "preset content sha256 header",
+			Finalizer:           SigV4Finalizer{},
+			Body:                io.LimitReader(strings.NewReader("payload"), 7),
+			Header:              http.Header{"X-Amz-Content-Sha256": {"preset"}},
+			ExpectContentSHA256: "preset",
+		},
+		{
+			Name:      "non-seekable body",
+			Finalizer: SigV4Finalizer{},
+			Body:      io.LimitReader(strings.NewReader("payload"), 7),
+			ExpectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			b := tc.Finalizer
+			b.Credentials = testCredentials
+			b.Region = "us-east-1"
+			b.Service = "s3"
+			h, sent := newSigV4TestHandler(http.MethodPut, "https://example.amazonaws.com/bucket/key", tc.Body, tc.Header, b.Finalizer)
+			_, _, err := h.Handle(context.Background(), nil)
+			if (err != nil) != tc.ExpectErr {
+				t.Fatalf("expect err is %v, got %v", tc.ExpectErr, err)
+			}
+			if tc.ExpectErr {
+				return
+			}
+
+			req := (*sent)[0]
+			if v := req.Header.Get("X-Amz-Content-Sha256"); v != tc.ExpectContentSHA256 {
+				t.Fatalf("expect x-amz-content-sha256 is %s, got %s", tc.ExpectContentSHA256, v)
+			}
+			if v := req.Header.Get("Authorization"); !strings.Contains(v, "x-amz-content-sha256") {
+				t.Fatalf("expect x-amz-content-sha256 is signed, got %s", v)
+			}
+			body, _ := io.ReadAll(req.Body)
+			if string(body) != "payload" {
+				t.Fatalf("expect body is payload, got %q", body)
+			}
+		})
+	}
+}
+
+func TestSigV4FinalizerSessionToken(t *testing.T) {
+	b := SigV4Finalizer{
+		Credentials: StaticCredentialsProvider{
+			AccessKeyID:     "AKIDEXAMPLE",
+			SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+			SessionToken:    "token",
+		},
+		Region:  "us-east-1",
+		Service: "service",
+	}
+	h, sent := newSigV4TestHandler(http.MethodGet, "https://example.amazonaws.com/", nil, nil, b.Finalizer)
+	_, _, err := h.Handle(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("expect no err, got %v", err)
+	}
+
+	req := (*sent)[0]
+	if v := req.Header.Get("X-Amz-Security-Token"); v != "token" {
+		t.Fatalf("expect x-amz-security-token is token, got %s", v)
+	}
+	if v := req.Header.Get("Authorization"); !strings.Contains(v, "SignedHeaders=host;x-amz-date;x-amz-security-token,") {
+		t.Fatalf("expect x-amz-security-token is signed, got %s", v)
+	}
+}
+
+type fixedClockSkew time.Duration
+
+func (s fixedClockSkew) ClockSkew() time.Duration { return time.Duration(s) }
+
+func TestSigV4FinalizerRetry(t *testing.T) {
+	now := testTime
+	b := SigV4Finalizer{
+		Credentials: testCredentials,
+		Region:      "us-east-1",
+		Service:     "service",
+		Now: func() time.Time {
+			now = now.Add(time.Second)
+			return now
+		},
+		ClockSkew: fixedClockSkew(-time.Second),
+	}
+	retry := request.RetryBuilder{
+		Retryer: request.BasicRetryer{Options: request.BasicRetryerOptions{Delayer: request.NopRetryDelayer}},
+	}
+	h, sent := newSigV4TestHandler(http.MethodPut, "https://example.amazonaws.com/", strings.NewReader("payload"), nil,
+		httpc.ComposeFinalizer(request.AttemptCountFinalizer{}.Finalizer, b.Finalizer), retry.Builder)
+	do := h.Do
+	h.Do = func(req *http.Request) (*http.Response, error) {
+		resp, err := do(req)
+		if len(*sent) == 1 {
+			resp.StatusCode = http.StatusServiceUnavailable
+		}
+		return resp, err
+	}
+	h.Deserializer = func(deserialize httpc.DeserializeFunc) httpc.DeserializeFunc {
+		return func(req *http.Request) (output httpc.DeserializeOutput, md httpc.Metadata, err error) {
+			output, md, err = deserialize(req)
+			if err == nil && output.Response.StatusCode != http.StatusOK {
+				err = &httpc.ResponseError{Response: output.Response, Err: io.ErrUnexpectedEOF}
+			}
+			return
+		}
+	}
+	_, _, err := h.Handle(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("expect no err, got %v", err)
+	}
+
+	if len(*sent) != 2 {
+		t.Fatalf("expect 2 attempts, got %d", len(*sent))
+	}
+	for i, expect := range []string{"20150830T123600Z", "20150830T123601Z"} {
+		if v := (*sent)[i].Header.Get("X-Amz-Date"); v != expect {
+			t.Fatalf("expect attempt %d x-amz-date is %s, got %s", i+1, expect, v)
+		}
+	}
+	if (*sent)[0].Header.Get("Authorization") == (*sent)[1].Header.Get("Authorization") {
+		t.Fatalf("expect every attempt is signed again")
+	}
+	for i, req := range *sent {
+		if v := req.Header.Get("Authorization"); !strings.Contains(v, ";x-request-attempt,") {
+			t.Fatalf("expect attempt %d x-request-attempt is signed, got %s", i+1, v)
+		}
+	}
+}
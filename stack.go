@@ -0,0 +1,198 @@
+package httpc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Stage is the name of a Handler stage.
+type Stage string
+
+const (
+	StageInitialize  Stage = "Initialize"
+	StageSerialize   Stage = "Serialize"
+	StageBuild       Stage = "Build"
+	StageFinalize    Stage = "Finalize"
+	StageDeserialize Stage = "Deserialize"
+)
+
+// RelativePosition specifies where a step is added or inserted.
+type RelativePosition int
+
+const (
+	// Before adds a step to the front of the steps,
+	// or inserts a step before the relative step.
+	Before RelativePosition = iota
+	// After adds a step to the back of the steps,
+	// or inserts a step after the relative step.
+	After
+)
+
+// StepNotFoundError is returned when the step with ID does not exist.
+type StepNotFoundError struct {
+	ID string
+}
+
+func (e *StepNotFoundError) Error() string {
+	return fmt.Sprintf("step %s not found", e.ID)
+}
+
+// StepExistsError is returned when the step with ID already exists.
+type StepExistsError struct {
+	ID string
+}
+
+func (e *StepExistsError) Error() string {
+	return fmt.Sprintf("step %s already exists", e.ID)
+}
+
+// Steps is an ordered list of steps with unique IDs.
+// The zero value is an empty list ready to use.
+type Steps[T any] struct {
+	ids   []string
+	steps []T
+}
+
+func (s *Steps[T]) index(id string) int {
+	for i, sid := range s.ids {
+		if sid == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Steps[T]) insertAt(i int, id string, step T) {
+	var zero T
+	s.ids = append(s.ids, "")
+	copy(s.ids[i+1:], s.ids[i:])
+	s.ids[i] = id
+	s.steps = append(s.steps, zero)
+	copy(s.steps[i+1:], s.steps[i:])
+	s.steps[i] = step
+}
+
+// Add adds the step to the front (Before) or the back (After) of the steps.
+func (s *Steps[T]) Add(id string, step T, pos RelativePosition) error {
+	if s.index(id) >= 0 {
+		return &StepExistsError{ID: id}
+	}
+	if pos == Before {
+		s.insertAt(0, id, step)
+	} else {
+		s.insertAt(len(s.ids), id, step)
+	}
+	return nil
+}
+
+// Insert inserts the step before or after the step with relativeTo ID.
+func (s *Steps[T]) Insert(id string, step T, relativeTo string, pos RelativePosition) error {
+	if s.index(id) >= 0 {
+		return &StepExistsError{ID: id}
+	}
+	i := s.index(relativeTo)
+	if i < 0 {
+		return &StepNotFoundError{ID: relativeTo}
+	}
+	if pos == After {
+		i++
+	}
+	s.insertAt(i, id, step)
+	return nil
+}
+
+// Swap replaces the step with ID and returns the replaced step.
+func (s *Steps[T]) Swap(id string, step T) (old T, err error) {
+	i := s.index(id)
+	if i < 0 {
+		return old, &StepNotFoundError{ID: id}
+	}
+	old = s.steps[i]
+	s.steps[i] = step
+	return old, nil
+}
+
+// Remove removes the step with ID and returns the removed step.
+func (s *Steps[T]) Remove(id string) (old T, err error) {
+	i := s.index(id)
+	if i < 0 {
+		return old, &StepNotFoundError{ID: id}
+	}
+	old = s.steps[i]
+	s.ids = append(s.ids[:i], s.ids[i+1:]...)
+	s.steps = append(s.steps[:i], s.steps[i+1:]...)
+	return old, nil
+}
+
+// Get returns the step with ID.
+func (s *Steps[T]) Get(id string) (step T, ok bool) {
+	i := s.index(id)
+	if i < 0 {
+		return step, false
+	}
+	return s.steps[i], true
+}
+
+// List returns the ordered step IDs.
+func (s *Steps[T]) List() []string {
+	ids := make([]string, len(s.ids))
+	copy(ids, s.ids)
+	return ids
+}
+
+// Clone returns a copy of s.
+func (s *Steps[T]) Clone() Steps[T] {
+	var s2 Steps[T]
+	if len(s.ids) > 0 {
+		s2.ids = make([]string, len(s.ids))
+		copy(s2.ids, s.ids)
+		s2.steps = make([]T, len(s.steps))
+		copy(s2.steps, s.steps)
+	}
+	return s2
+}
+
+// Stack holds the named steps of every Handler stage.
+// The zero value is an empty stack ready to use.
+//
+// Each step's ID should be unique within its stage.
+// Middlewares provided by this module return their default step ID from the ID method.
+type Stack struct {
+	Initialize  Steps[Initializer]
+	Serialize   Steps[Serializer]
+	Build       Steps[Builder]
+	Finalize    Steps[Finalizer]
+	Deserialize Steps[Deserializer]
+}
+
+// Clone returns a copy of s.
+// Changing the steps of the copy does not affect s.
+func (s *Stack) Clone() *Stack {
+	return &Stack{
+		Initialize:  s.Initialize.Clone(),
+		Serialize:   s.Serialize.Clone(),
+		Build:       s.Build.Clone(),
+		Finalize:    s.Finalize.Clone(),
+		Deserialize: s.Deserialize.Clone(),
+	}
+}
+
+// String returns the ordered step IDs of every stage for debugging.
+func (s *Stack) String() string {
+	var b strings.Builder
+	writeStage := func(stage Stage, ids []string) {
+		b.WriteString(string(stage))
+		b.WriteString(":\n")
+		for _, id := range ids {
+			b.WriteString("  ")
+			b.WriteString(id)
+			b.WriteByte('\n')
+		}
+	}
+	writeStage(StageInitialize, s.Initialize.List())
+	writeStage(StageSerialize, s.Serialize.List())
+	writeStage(StageBuild, s.Build.List())
+	writeStage(StageFinalize, s.Finalize.List())
+	writeStage(StageDeserialize, s.Deserialize.List())
+	return b.String()
+}
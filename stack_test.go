@@ -0,0 +1,147 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestSteps(t *testing.T) {
+	var steps Steps[int]
+	ops := []struct {
+		Name string
+		Op   func() error
+
+		ExpectIDs   []string
+		ExpectError error
+	}{
+		{
+			Name:      "add back",
+			Op:        func() error { return steps.Add("b", 2, After) },
+			ExpectIDs: []string{"b"},
+		},
+		{
+			Name:      "add front",
+			Op:        func() error { return steps.Add("a", 1, Before) },
+			ExpectIDs: []string{"a", "b"},
+		},
+		{
+			Name:        "add exists",
+			Op:          func() error { return steps.Add("a", 1, After) },
+			ExpectIDs:   []string{"a", "b"},
+			ExpectError: &StepExistsError{ID: "a"},
+		},
+		{
+			Name:      "insert after",
+			Op:        func() error { return steps.Insert("c", 3, "b", After) },
+			ExpectIDs: []string{"a", "b", "c"},
+		},
+		{
+			Name:      "insert before",
+			Op:        func() error { return steps.Insert("a1", 11, "b", Before) },
+			ExpectIDs: []string{"a", "a1", "b", "c"},
+		},
+		{
+			Name:        "insert relative to missing",
+			Op:          func() error { return steps.Insert("d", 4, "x", After) },
+			ExpectIDs:   []string{"a", "a1", "b", "c"},
+			ExpectError: &StepNotFoundError{ID: "x"},
+		},
+		{
+			Name: "swap",
+			Op: func() error {
+				old, err := steps.Swap("b", 22)
+				if err == nil && old != 2 {
+					return errors.New("unexpected old step")
+				}
+				return err
+			},
+			ExpectIDs: []string{"a", "a1", "b", "c"},
+		},
+		{
+			Name: "remove",
+			Op: func() error {
+				old, err := steps.Remove("a1")
+				if err == nil && old != 11 {
+					return errors.New("unexpected old step")
+				}
+				return err
+			},
+			ExpectIDs: []string{"a", "b", "c"},
+		},
+		{
+			Name: "remove missing",
+			Op: func() error {
+				_, err := steps.Remove("a1")
+				return err
+			},
+			ExpectIDs:   []string{"a", "b", "c"},
+			ExpectError: &StepNotFoundError{ID: "a1"},
+		},
+	}
+	for _, op := range ops {
+		err := op.Op()
+		if !reflect.DeepEqual(op.ExpectError, err) {
+			t.Fatalf("%s: expect err is %v, got %v", op.Name, op.ExpectError, err)
+		}
+		if ids := steps.List(); !reflect.DeepEqual(op.ExpectIDs, ids) {
+			t.Fatalf("%s: expect ids are %v, got %v", op.Name, op.ExpectIDs, ids)
+		}
+	}
+
+	if step, ok := steps.Get("b"); !ok || step != 22 {
+		t.Fatalf("expect step b is 22, got %d", step)
+	}
+
+	clone := steps.Clone()
+	clone.Remove("a")
+	if ids := steps.List(); len(ids) != 3 {
+		t.Fatalf("expect clone does not affect steps, got %v", ids)
+	}
+}
+
+func TestHandlerStack(t *testing.T) {
+	var calls []string
+	record := func(name string) Builder {
+		return func(build BuildFunc) BuildFunc {
+			return func(ctx context.Context, req *Request) (interface{}, Metadata, error) {
+				calls = append(calls, name)
+				return build(ctx, req)
+			}
+		}
+	}
+
+	stack := &Stack{}
+	stack.Serialize.Add("Request", func(serialize SerializeFunc) SerializeFunc {
+		return func(ctx context.Context, input SerializeInput) (interface{}, Metadata, error) {
+			input.Request, _ = NewRequest(ctx, http.MethodGet, "http://example.com", nil)
+			return serialize(ctx, input)
+		}
+	}, After)
+	stack.Build.Add("b", record("b"), After)
+	stack.Build.Add("a", record("a"), Before)
+	stack.Build.Insert("c", record("c"), "b", After)
+
+	h := Handler{
+		Builder: record("field"),
+		Stack:   stack,
+		Do: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+		},
+	}
+	_, _, err := h.Handle(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("expect no err, got %s", err)
+	}
+	expectCalls := []string{"field", "a", "b", "c"}
+	if !reflect.DeepEqual(expectCalls, calls) {
+		t.Fatalf("expect calls are %v, got %v", expectCalls, calls)
+	}
+
+	expectString := "Initialize:\nSerialize:\n  Request\nBuild:\n  a\n  b\n  c\nFinalize:\nDeserialize:\n"
+	if s := stack.String(); expectString != s {
+		t.Fatalf("expect stack string is %q, got %q", expectString, s)
+	}
+}
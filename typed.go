@@ -0,0 +1,91 @@
+package httpc
+
+import (
+	"context"
+	"fmt"
+)
+
+// TypeMismatchError is returned when a typed stage function or TypedHandler
+// receives a value of unexpected type.
+type TypeMismatchError struct {
+	Expect interface{}
+	Got    interface{}
+}
+
+func (e *TypeMismatchError) Error() string {
+	return fmt.Sprintf("expect %T type, got %T", e.Expect, e.Got)
+}
+
+func assertType[T any](v interface{}) (*T, error) {
+	if v == nil {
+		return nil, nil
+	}
+	t, ok := v.(*T)
+	if !ok {
+		return nil, &TypeMismatchError{Expect: (*T)(nil), Got: v}
+	}
+	return t, nil
+}
+
+type TypedInitializeFunc[In any] func(ctx context.Context, input *In) (output interface{}, md Metadata, err error)
+
+// TypedInitializer is an Initializer which receives the input as *In.
+type TypedInitializer[In any] func(TypedInitializeFunc[In]) TypedInitializeFunc[In]
+
+func (ini TypedInitializer[In]) Initializer(initialize InitializeFunc) InitializeFunc {
+	next := func(ctx context.Context, input *In) (interface{}, Metadata, error) {
+		return initialize(ctx, input)
+	}
+	typedInitialize := ini(next)
+	return func(ctx context.Context, input interface{}) (output interface{}, md Metadata, err error) {
+		in, err := assertType[In](input)
+		if err != nil {
+			return output, md, err
+		}
+		return typedInitialize(ctx, in)
+	}
+}
+
+type TypedSerializeInput[In any] struct {
+	Input   *In
+	Request *Request
+}
+
+type TypedSerializeFunc[In any] func(ctx context.Context, input TypedSerializeInput[In]) (output interface{}, md Metadata, err error)
+
+// TypedSerializer is a Serializer which receives the input as *In.
+type TypedSerializer[In any] func(TypedSerializeFunc[In]) TypedSerializeFunc[In]
+
+func (s TypedSerializer[In]) Serializer(serialize SerializeFunc) SerializeFunc {
+	next := func(ctx context.Context, input TypedSerializeInput[In]) (interface{}, Metadata, error) {
+		return serialize(ctx, SerializeInput{Input: input.Input, Request: input.Request})
+	}
+	typedSerialize := s(next)
+	return func(ctx context.Context, input SerializeInput) (output interface{}, md Metadata, err error) {
+		in, err := assertType[In](input.Input)
+		if err != nil {
+			return output, md, err
+		}
+		return typedSerialize(ctx, TypedSerializeInput[In]{Input: in, Request: input.Request})
+	}
+}
+
+// TypedHandler wraps Handler with typed input and output.
+//
+// The input passed to the underlying Handler is *In,
+// and the output returned by the underlying Handler must be *Out or nil.
+type TypedHandler[In, Out any] struct {
+	Handler Handler
+}
+
+func (h TypedHandler[In, Out]) Handle(ctx context.Context, input *In, optFns ...func(*Options)) (
+	output *Out, md Metadata, err error,
+) {
+	var out interface{}
+	out, md, err = h.Handler.Handle(ctx, input, optFns...)
+	if err != nil {
+		return
+	}
+	output, err = assertType[Out](out)
+	return
+}
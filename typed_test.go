@@ -0,0 +1,111 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestTypedHandler(t *testing.T) {
+	type Input struct{ N int }
+	type Output struct{ N int }
+
+	testCases := []struct {
+		Name   string
+		Input  *Input
+		Output interface{}
+
+		ExpectOutput *Output
+		ExpectError  string
+	}{
+		{
+			Name:         "typed output",
+			Input:        &Input{N: 1},
+			Output:       &Output{N: 2},
+			ExpectOutput: &Output{N: 2},
+		},
+		{
+			Name:  "nil output",
+			Input: &Input{N: 1},
+		},
+		{
+			Name:        "mismatched output",
+			Input:       &Input{N: 1},
+			Output:      &Input{N: 2},
+			ExpectError: "expect *httpc.Output type, got *httpc.Input",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var gotInput *Input
+			h := TypedHandler[Input, Output]{
+				Handler: Handler{
+					Initializer: TypedInitializer[Input](func(initialize TypedInitializeFunc[Input]) TypedInitializeFunc[Input] {
+						return func(ctx context.Context, input *Input) (interface{}, Metadata, error) {
+							gotInput = input
+							return initialize(ctx, input)
+						}
+					}).Initializer,
+					Serializer: TypedSerializer[Input](func(serialize TypedSerializeFunc[Input]) TypedSerializeFunc[Input] {
+						return func(ctx context.Context, input TypedSerializeInput[Input]) (interface{}, Metadata, error) {
+							if input.Input != gotInput {
+								return nil, Metadata{}, errors.New("unexpected serialize input")
+							}
+							input.Request, _ = NewRequest(ctx, http.MethodGet, "http://example.com", nil)
+							return serialize(ctx, input)
+						}
+					}).Serializer,
+					Builder: ComposeBuilder(),
+					Deserializer: func(deserialize DeserializeFunc) DeserializeFunc {
+						return func(req *http.Request) (output DeserializeOutput, md Metadata, err error) {
+							output, md, err = deserialize(req)
+							output.Output = tc.Output
+							return
+						}
+					},
+					Do: func(req *http.Request) (*http.Response, error) {
+						return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+					},
+				},
+			}
+
+			output, _, err := h.Handle(context.Background(), tc.Input)
+			if gotInput != tc.Input {
+				t.Fatalf("expect input is %p, got %p", tc.Input, gotInput)
+			}
+			if tc.ExpectError != "" {
+				if err == nil {
+					t.Fatalf("expect err is %s, got none", tc.ExpectError)
+				}
+				if tc.ExpectError != err.Error() {
+					t.Fatalf("expect err is %s, got %s", tc.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expect no err, got %s", err)
+			}
+			if (tc.ExpectOutput == nil) != (output == nil) ||
+				(output != nil && *output != *tc.ExpectOutput) {
+				t.Fatalf("expect output is %v, got %v", tc.ExpectOutput, output)
+			}
+		})
+	}
+}
+
+func TestTypedInitializerTypeMismatch(t *testing.T) {
+	type Input struct{}
+	initialize := TypedInitializer[Input](func(initialize TypedInitializeFunc[Input]) TypedInitializeFunc[Input] {
+		return initialize
+	}).Initializer(func(ctx context.Context, input interface{}) (interface{}, Metadata, error) {
+		return nil, Metadata{}, nil
+	})
+
+	_, _, err := initialize(context.Background(), "input")
+	var terr *TypeMismatchError
+	if !errors.As(err, &terr) {
+		t.Fatalf("expect err is %T, got %v", terr, err)
+	}
+}